@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/sendgrid"
+	"github.com/reverie/types"
+)
+
+// commands holds the administrative subcommands of the binary in the form of <name>:<handler>
+// Usage: reverie <command> [flags]
+var commands = map[string]func(args []string) error{
+	"reconcile-inventory":  reconcileInventory,
+	"migrate-catalog":      migrateCatalog,
+	"migrate-reservations": migrateReservations,
+	"send-digests":         sendDigests,
+}
+
+// runCommand runs an administrative subcommand and returns the exit code
+func runCommand(name string, args []string) int {
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n", name)
+		return 2
+	}
+	if err := command(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// formatInventory prints the non-zero items of an inventory
+func formatInventory(inventory types.Inventory) string {
+	items := make([]string, 0)
+	for key, value := range inventory {
+		if value != 0 {
+			items = append(items, fmt.Sprintf("%s=%d", key, value))
+		}
+	}
+	if len(items) == 0 {
+		return "-"
+	}
+	sort.Strings(items)
+	return strings.Join(items, " ")
+}
+
+// reconcileInventory prints the drift between the vendors' reservations and their accepted offers
+// and corrects it if the -apply flag is provided
+func reconcileInventory(args []string) error {
+	flags := flag.NewFlagSet("reconcile-inventory", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "correct the reservation ledger as per the accepted offers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := mongo.ReconcileInventories(*apply)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VENDOR\tPOST\tEXPECTED\tRECORDED")
+	for _, vendor := range report.Vendors {
+		for _, drift := range vendor.Drifts {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", vendor.Vendor, drift.PostID.Hex(), formatInventory(drift.Expected), formatInventory(drift.Recorded))
+		}
+		fmt.Fprintf(writer, "%s\tFREE\t%s\t%s\n", vendor.Vendor, formatInventory(vendor.ExpectedFree), formatInventory(vendor.RecordedFree))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	for _, offer := range report.UnreadableOffers {
+		fmt.Printf("Could not decrypt accepted offer %s on post %s\n", offer.OfferKey, offer.PostID.Hex())
+	}
+	for _, vendor := range report.MissingVendors {
+		fmt.Printf("Vendor %s no longer exists, its reservations have to be reviewed manually\n", vendor)
+	}
+
+	switch {
+	case report.Drifted == 0:
+		fmt.Println("All vendor reservations are consistent with the accepted offers")
+	case *apply:
+		fmt.Printf("Corrected the reservations of %d vendors\n", report.Drifted)
+	default:
+		fmt.Printf("Found drift in the reservations of %d vendors, run with -apply to correct it\n", report.Drifted)
+	}
+	return nil
+}
+
+// migrateCatalog seeds the equipment catalog and registers the equipment keys found within existing documents
+func migrateCatalog(args []string) error {
+	registered, err := mongo.MigrateCatalog()
+	if err != nil {
+		return err
+	}
+	if len(registered) == 0 {
+		fmt.Println("All equipments within existing documents are present in the catalog")
+		return nil
+	}
+	sort.Strings(registered)
+	fmt.Printf("Registered %d unknown equipments as inactive, review them via the admin catalog API: %s\n", len(registered), strings.Join(registered, ", "))
+	return nil
+}
+
+// migrateReservations converts the vendors' inventories into their total capacity
+// by moving the quantities of accepted offers on active posts into reservations
+// It has to be run once after upgrading and before the reservations are reconciled
+func migrateReservations(args []string) error {
+	migrated, unreadable, err := mongo.MigrateReservations()
+	for _, offer := range unreadable {
+		fmt.Printf("Could not decrypt accepted offer %s on post %s\n", offer.OfferKey, offer.PostID.Hex())
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Moved %d accepted offers from the vendors' inventories into reservations\n", migrated)
+	if len(unreadable) > 0 {
+		return fmt.Errorf("Migration is incomplete, fix the keys of %d accepted offers and run it again", len(unreadable))
+	}
+	return nil
+}
+
+// sendDigests emails every vendor the posts which matched its saved searches since its last digest
+// Meant to be run once a day, alerts which couldn't be emailed stay pending for the next run
+func sendDigests(args []string) error {
+	digests, err := mongo.FetchPendingDigests()
+	if err != nil {
+		return err
+	}
+	sent, failed := 0, 0
+	for idx := range digests {
+		digest := &digests[idx]
+		vendor, err := mongo.FetchSingleUserWithoutPassword(digest.Vendor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not fetch vendor %s: %v\n", digest.Vendor, err)
+			failed++
+			continue
+		}
+		if vendor.IsSuspended() {
+			continue
+		}
+		if err := sendgrid.SendSearchDigestEmail(vendor.GetName(), vendor.GetEmail(), digest.Alerts); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not email the digest of vendor %s: %v\n", digest.Vendor, err)
+			failed++
+			continue
+		}
+		sent++
+		if err := mongo.MarkDigested(digest); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not mark the digest of vendor %s as sent: %v\n", digest.Vendor, err)
+			failed++
+		}
+	}
+	fmt.Printf("Sent %d of %d digests\n", sent, len(digests))
+	if failed > 0 {
+		return fmt.Errorf("Failed to send %d digests", failed)
+	}
+	return nil
+}
@@ -12,4 +12,13 @@ var (
 
 	// JWTConfig is the configuration for json web auth token
 	JWTConfig = Project.JWT
+
+	// BillingConfig is the configuration for invoices
+	BillingConfig = Project.Billing
+
+	// PaymentsConfig is the configuration for the payment gateway
+	PaymentsConfig = Project.Payments
+
+	// MatchingConfig is the configuration for inviting vendors to new posts
+	MatchingConfig = Project.Matching
 )
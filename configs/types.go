@@ -34,6 +34,30 @@ type SendGrid struct {
 	FrontendEndpoint string `toml:"frontend_endpoint"`
 }
 
+// Billing is the configuration for the invoices generated on completion of posts
+type Billing struct {
+	// Percentage of the subtotal charged for our services
+	PlatformFeePercent float64 `toml:"platform_fee_percent"`
+	// Percentage of GST levied on the subtotal and the platform fee
+	GSTPercent float64 `toml:"gst_percent"`
+	// Prefix of the sequential invoice numbers, Ex:- EZF-000042
+	InvoicePrefix string `toml:"invoice_prefix"`
+}
+
+// Payments is the configuration for the payment gateway
+type Payments struct {
+	// Name of the payment gateway, "fake" approves every payment without moving any money
+	Provider string `toml:"provider"`
+}
+
+// Matching is the configuration for inviting vendors to newly created posts
+type Matching struct {
+	// Vendors whose fleet is based within this distance of a post are invited to it
+	RadiusKm float64 `toml:"radius_km"`
+	// Maximum number of vendors invited to a post
+	MaxInvitations int `toml:"max_invitations"`
+}
+
 // ProjectCfg is the configuration for the entire project
 type ProjectCfg struct {
 	Debug    bool     `toml:"debug"`
@@ -43,4 +67,7 @@ type ProjectCfg struct {
 	Mongo    Mongo    `toml:"mongo"`
 	JWT      JWT      `toml:"jwt"`
 	SendGrid SendGrid `toml:"sendgrid"`
+	Billing  Billing  `toml:"billing"`
+	Payments Payments `toml:"payments"`
+	Matching Matching `toml:"matching"`
 }
@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// reconcileInventories rebuilds the vendors' expected reservations from the accepted offers and reports the drift
+func reconcileInventories(c *fiber.Ctx, apply bool) error {
+	report, err := mongo.ReconcileInventories(apply)
+	if err == mongo.ErrReservationsNotMigrated {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return utils.ServerError("Admin-Controller-1", err, c)
+	}
+	if apply {
+		recordAudit(c, &types.AuditEntry{
+			Action: types.AuditReconcileInventory,
+			After:  report,
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        report,
+	})
+}
+
+// PreviewInventoryReconciliation reports the drift between the vendors' reservations and the accepted offers
+// without making any changes
+func PreviewInventoryReconciliation(c *fiber.Ctx) error {
+	return reconcileInventories(c, false)
+}
+
+// ApplyInventoryReconciliation corrects the vendors' reservations to match the accepted offers
+// and reports the drift which was corrected
+func ApplyInventoryReconciliation(c *fiber.Ctx) error {
+	return reconcileInventories(c, true)
+}
+
+// parseAdminAction parses the optional body of admin requests which change the status of a user or a post
+func parseAdminAction(c *fiber.Ctx) (*types.AdminAction, error) {
+	action := &types.AdminAction{}
+	if len(c.Body()) == 0 {
+		return action, nil
+	}
+	if err := c.BodyParser(action); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return action, nil
+}
+
+// SearchUsers returns the users matching the optional "q", "role", "suspended" and "unverified" query parameters
+func SearchUsers(c *fiber.Ctx) error {
+	pageNumber, err := strconv.ParseInt(c.Query("page", "0"), 10, 64)
+	if err != nil || pageNumber < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Page must be a non-negative number")
+	}
+	query := &types.UserQuery{
+		Search:     c.Query("q"),
+		Role:       c.Query("role"),
+		Suspended:  c.Query("suspended") == "true",
+		Unverified: c.Query("unverified") == "true",
+	}
+	if query.Role != types.EMPTY && query.Role != types.Client && query.Role != types.Vendor && query.Role != types.Admin {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s is an invalid role", query.Role))
+	}
+	users, err := mongo.SearchUsers(query, pageNumber)
+	if err != nil {
+		return utils.ServerError("Admin-Controller-2", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"page":        pageNumber,
+		"data":        users,
+	})
+}
+
+// fetchUser returns the user given by the "email" parameter
+func fetchUser(c *fiber.Ctx) (*types.User, error) {
+	user, err := mongo.FetchSingleUserWithoutPassword(c.Params("email"))
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "No such user exists")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Admin-Controller-3", err, c)
+	}
+	return user, nil
+}
+
+// FetchUserByAdmin returns the entire profile of any user including its role and status
+func FetchUserByAdmin(c *fiber.Ctx) error {
+	user, err := fetchUser(c)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"role":        user.GetRole(),
+		"verified":    user.IsVerified(),
+		"data":        user,
+	})
+}
+
+// VerifyUser marks a user's email as verified on behalf of the user
+func VerifyUser(c *fiber.Ctx) error {
+	user, err := fetchUser(c)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified() {
+		return fiber.NewError(fiber.StatusConflict, "User is already verified")
+	}
+	if err := mongo.VerifyUser(user.GetEmail()); err != nil {
+		return utils.ServerError("Admin-Controller-4", err, c)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditVerifyUser,
+		Target: user.GetEmail(),
+	})
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// setUserSuspended suspends or lifts the suspension of the user given by the "email" parameter
+func setUserSuspended(c *fiber.Ctx, suspended bool) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	if suspended && action.Reason == types.EMPTY {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'reason' is required for suspending a user")
+	}
+	user, err := fetchUser(c)
+	if err != nil {
+		return err
+	}
+	if user.GetRole() == types.Admin {
+		return fiber.NewError(fiber.StatusForbidden, "Admins cannot be suspended")
+	}
+	if user.IsSuspended() == suspended {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("User's suspension is already set to %t", suspended))
+	}
+	if err := mongo.SetUserSuspended(user.GetEmail(), suspended); err != nil {
+		return utils.ServerError("Admin-Controller-5", err, c)
+	}
+
+	auditAction := types.AuditSuspendUser
+	if !suspended {
+		auditAction = types.AuditUnsuspendUser
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: auditAction,
+		Target: user.GetEmail(),
+		Reason: action.Reason,
+	})
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// SuspendUser suspends a user's account, suspended users cannot log in or use the platform
+func SuspendUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, true)
+}
+
+// UnsuspendUser lifts the suspension of a user's account
+func UnsuspendUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, false)
+}
+
+// UpdateInventoryByAdmin replaces the entire inventory of a vendor
+// The inventory cannot be reduced below the amount reserved for accepted offers
+func UpdateInventoryByAdmin(c *fiber.Ctx) error {
+	inventory := types.Inventory{}
+	if err := c.BodyParser(&inventory); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := validateInventory(c, inventory); err != nil {
+		return err
+	}
+	vendorEmail := utils.ImmutableString(c.Params("email"))
+	previous, err := mongo.ReplaceVendorInventory(vendorEmail, inventory)
+	if err == mongo.ErrNoDocuments {
+		return fiber.NewError(fiber.StatusNotFound, "No such vendor exists")
+	}
+	if err == mongo.ErrInventoryReserved {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	if err == mongo.ErrConflict {
+		return fiber.NewError(fiber.StatusConflict, "Vendor's reservations were changed by another request, please try again")
+	}
+	if err != nil {
+		return utils.ServerError("Admin-Controller-6", err, c)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditUpdateInventory,
+		Target: vendorEmail,
+		Before: previous,
+		After:  inventory,
+	})
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// FetchPostByAdmin returns any post along with its pending and accepted offers
+// The email IDs of the vendors behind the offer keys are revealed as well
+func FetchPostByAdmin(c *fiber.Ctx) error {
+	post, err := mongo.FetchSinglePostByClient(c.Params("id"))
+	if err == mongo.ErrNoDocuments {
+		return fiber.NewError(fiber.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return utils.ServerError("Admin-Controller-7", err, c)
+	}
+	vendors := make(map[string]string)
+	for _, offers := range []map[string]types.Offer{post.Offers, post.AcceptedOffers} {
+		for offerKey := range offers {
+			vendorEmail, err := utils.Decrypt(offerKey)
+			if err != nil {
+				utils.LogError("Admin-Controller-8", err)
+				continue
+			}
+			vendors[offerKey] = vendorEmail
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"owner":       post.Owner,
+		"vendors":     vendors,
+		"data":        post,
+	})
+}
+
+// TransitionPostByAdmin moves any post into a new status as per the post lifecycle on behalf of its owner
+// A requested completion is rejected by moving the post back to ONGOING
+// Approval of the completion is handled separately by CompletePostByAdmin as it generates the invoice
+func TransitionPostByAdmin(c *fiber.Ctx) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	if !validator.IsIn(action.Status, types.OPEN, types.ONGOING, types.DELETED) {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'status' should be either OPEN, ONGOING or DELETED")
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Admin-Controller-9", utils.ErrFailedExtraction, c)
+	}
+	postID := utils.ImmutableString(c.Params("id"))
+	if err := changePostStatus(c, postID, claims.GetEmail(), action.Status, action.Reason); err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// CompletePostByAdmin approves the completion requested by the client and generates the post's invoice
+// Completions disputed by any vendor can only be approved with a reason
+func CompletePostByAdmin(c *fiber.Ctx) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Admin-Controller-10", utils.ErrFailedExtraction, c)
+	}
+	postID := utils.ImmutableString(c.Params("id"))
+	invoice, err := completePost(c, postID, claims.GetEmail(), action.Reason)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"invoice":     invoice,
+	})
+}
+
+// FetchInventoryRequests returns the inventory change requests with the status given by the "status" query parameter
+// along with the difference between the vendor's current and requested inventory and specifications, PENDING requests
+// are returned by default
+func FetchInventoryRequests(c *fiber.Ctx) error {
+	pageNumber, err := strconv.ParseInt(c.Query("page", "0"), 10, 64)
+	if err != nil || pageNumber < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Page must be a non-negative number")
+	}
+	status := c.Query("status", types.PENDING)
+	if !validator.IsIn(status, types.PENDING, types.APPROVED, types.REJECTED) {
+		return fiber.NewError(fiber.StatusBadRequest, "Parameter status should be either PENDING, APPROVED or REJECTED")
+	}
+	requests, err := mongo.FetchInventoryRequests(status, pageNumber)
+	if err != nil {
+		return utils.ServerError("Admin-Controller-11", err, c)
+	}
+	data := make([]types.M, 0, len(requests))
+	for idx := range requests {
+		data = append(data, types.M{
+			"request":    requests[idx],
+			"diff":       requests[idx].Diff(requests[idx].Current),
+			"specs_diff": requests[idx].SpecsDiff(requests[idx].CurrentSpecs),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"page":        pageNumber,
+		"data":        data,
+	})
+}
+
+// fetchPendingInventoryRequest returns the PENDING inventory change request given by the "id" parameter
+func fetchPendingInventoryRequest(c *fiber.Ctx) (*types.InventoryRequest, error) {
+	request, err := mongo.FetchInventoryRequest(c.Params("id"))
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Inventory change request not found")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Admin-Controller-12", err, c)
+	}
+	if request.Status != types.PENDING {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Inventory change request has already been %s", request.Status))
+	}
+	return request, nil
+}
+
+// ApproveInventoryRequest applies a vendor's inventory change request to its inventory and specifications
+// The inventory cannot be reduced below the amount reserved for accepted offers
+func ApproveInventoryRequest(c *fiber.Ctx) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Admin-Controller-13", utils.ErrFailedExtraction, c)
+	}
+	request, err := fetchPendingInventoryRequest(c)
+	if err != nil {
+		return err
+	}
+	// Requested items might have been removed from the catalog since the request was raised
+	if err := validateInventory(c, request.Requested); err != nil {
+		return err
+	}
+	previous, err := mongo.ApproveInventoryRequest(request, claims.GetEmail(), action.Reason)
+	if err == mongo.ErrInventoryReserved {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	if err == mongo.ErrConflict {
+		return fiber.NewError(fiber.StatusConflict, "Inventory change request was changed by another request, please try again")
+	}
+	if err != nil {
+		return utils.ServerError("Admin-Controller-14", err, c)
+	}
+
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditApproveInventory,
+		Target: request.Vendor,
+		Before: previous,
+		After:  request.Requested,
+		Reason: action.Reason,
+	})
+	go mongo.NotifyInventoryRequestReview(request)
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"diff":        request.Diff(*previous),
+		"specs_diff":  request.SpecsDiff(request.CurrentSpecs),
+	})
+}
+
+// RejectInventoryRequest turns down a vendor's inventory change request
+func RejectInventoryRequest(c *fiber.Ctx) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	if action.Reason == types.EMPTY {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'reason' is required for rejecting a request")
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Admin-Controller-15", utils.ErrFailedExtraction, c)
+	}
+	request, err := fetchPendingInventoryRequest(c)
+	if err != nil {
+		return err
+	}
+	if err := mongo.RejectInventoryRequest(request, claims.GetEmail(), action.Reason); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Inventory change request was changed by another request, please try again")
+		}
+		return utils.ServerError("Admin-Controller-16", err, c)
+	}
+
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditRejectInventory,
+		Target: request.Vendor,
+		After:  request.Requested,
+		Reason: action.Reason,
+	})
+	go mongo.NotifyInventoryRequestReview(request)
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
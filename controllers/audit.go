@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// recordAudit appends an action performed by the current user to the audit log
+// The actor, the request's IP and the timestamp are filled in from the request
+// The action has already taken place, hence failures are logged instead of being returned
+func recordAudit(c *fiber.Ctx, entry *types.AuditEntry) {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		utils.LogError("Audit-Controller-1", utils.ErrFailedExtraction)
+		return
+	}
+	entry.Actor = claims.GetEmail()
+	entry.ActorRole = claims.Role
+	entry.IP = c.IP()
+	entry.Created = time.Now().Unix()
+	if err := mongo.InsertAuditEntry(entry); err != nil {
+		utils.LogError("Audit-Controller-2", err)
+	}
+}
+
+// recordOfferAudit appends an action on a vendor's offer on a post to the audit log
+func recordOfferAudit(c *fiber.Ctx, action, postID, vendorEmail string, before, after interface{}, reason string) {
+	offerKey, err := utils.Encrypt(vendorEmail)
+	if err != nil {
+		utils.LogError("Audit-Controller-4", err)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action:   action,
+		Target:   utils.ImmutableString(postID),
+		OfferKey: offerKey,
+		Vendor:   vendorEmail,
+		Before:   before,
+		After:    after,
+		Reason:   reason,
+	})
+}
+
+// fetchAuditLog returns a page of the audit entries matching the query
+func fetchAuditLog(c *fiber.Ctx, query *types.AuditQuery) error {
+	pageNumber, err := strconv.ParseInt(c.Query("page", "0"), 10, 64)
+	if err != nil || pageNumber < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Page must be a non-negative number")
+	}
+	entries, err := mongo.FetchAuditLog(query, pageNumber)
+	if err != nil {
+		return utils.ServerError("Audit-Controller-3", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"page":        pageNumber,
+		"data":        entries,
+	})
+}
+
+// FetchAuditLog returns the audit log filtered by the optional "actor", "action" and "target" query parameters
+func FetchAuditLog(c *fiber.Ctx) error {
+	return fetchAuditLog(c, &types.AuditQuery{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+	})
+}
+
+// FetchPostAuditLog returns the history of all changes made to the post given by the "id" parameter and its offers
+// The results can be narrowed down with the optional "action" query parameter
+func FetchPostAuditLog(c *fiber.Ctx) error {
+	return fetchAuditLog(c, &types.AuditQuery{
+		Action: c.Query("action"),
+		Target: c.Params("id"),
+	})
+}
+
+// FetchUserAuditLog returns the actions performed by or concerning the user given by the "email" parameter
+// The results can be narrowed down with the optional "action" query parameter
+func FetchUserAuditLog(c *fiber.Ctx) error {
+	return fetchAuditLog(c, &types.AuditQuery{
+		Action: c.Query("action"),
+		User:   c.Params("email"),
+	})
+}
+
+// RestoreOffer puts back an offer which was retracted or rejected, as recorded by the audit entry given by the "id" parameter
+// The offer is restored as a pending offer, provided the post is still OPEN and the vendor hasn't made another offer since
+func RestoreOffer(c *fiber.Ctx) error {
+	action, err := parseAdminAction(c)
+	if err != nil {
+		return err
+	}
+	entryID := utils.ImmutableString(c.Params("id"))
+	entry, offer, err := mongo.FetchRemovedOffer(entryID)
+	if err == mongo.ErrNoDocuments {
+		return fiber.NewError(fiber.StatusNotFound, "No audit entry of a retracted or rejected offer exists with the given ID")
+	}
+	if err != nil {
+		return utils.ServerError("Audit-Controller-5", err, c)
+	}
+
+	// A restored offer has to be accepted again and reserve the vendor's inventory anew
+	offer.Accepted = 0
+	if err := mongo.RestoreOffer(entry.Target, entry.OfferKey, offer); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Offer can only be restored on an OPEN post on which the vendor holds no other offer")
+		}
+		return utils.ServerError("Audit-Controller-6", err, c)
+	}
+
+	reason := "Restored from audit entry " + entryID
+	if action.Reason != types.EMPTY {
+		reason += ": " + action.Reason
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action:   types.AuditRestoreOffer,
+		Target:   entry.Target,
+		OfferKey: entry.OfferKey,
+		Vendor:   entry.Vendor,
+		After:    offer,
+		Reason:   reason,
+	})
+
+	go mongo.NotifyVendorOnRestoration(entry.Target, entry.Vendor)
+	// Names are user input, hence they are escaped before being used within the message template
+	name := strings.ReplaceAll(offer.Name, "%", "%%")
+	go mongo.NotifyClient(entry.Target, name+"'s offer on your post %s has been restored by us")
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
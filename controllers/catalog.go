@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// validateInventory checks whether all items of an inventory are active in the equipment catalog and are non-negative
+// Used for validating requirements, offers and vendor inventories
+func validateInventory(c *fiber.Ctx, inventory types.Inventory) error {
+	catalog, err := mongo.FetchEquipmentKeys(true)
+	if err != nil {
+		return utils.ServerError("Catalog-Controller-1", err, c)
+	}
+	if err := inventory.Validate(catalog); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// FetchActiveCatalog returns all equipments which can currently be requested, offered and held in inventories
+func FetchActiveCatalog(c *fiber.Ctx) error {
+	catalog, err := mongo.FetchCatalog(true)
+	if err != nil {
+		return utils.ServerError("Catalog-Controller-2", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        catalog,
+	})
+}
+
+// FetchCatalog returns all equipments in the catalog including the inactive ones
+func FetchCatalog(c *fiber.Ctx) error {
+	catalog, err := mongo.FetchCatalog(false)
+	if err != nil {
+		return utils.ServerError("Catalog-Controller-3", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        catalog,
+	})
+}
+
+// CreateEquipment adds a new equipment to the catalog
+func CreateEquipment(c *fiber.Ctx) error {
+	equipment := &types.Equipment{}
+	if err := c.BodyParser(equipment); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if result, err := validator.ValidateStruct(equipment); !result {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if !types.IsValidEquipmentKey(equipment.Key) {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s is an invalid key, it should be alphanumeric starting with a letter and less than 28 characters", equipment.Key))
+	}
+
+	unique, err := mongo.IsUniqueEquipmentKey(equipment.Key)
+	if err != nil {
+		return utils.ServerError("Catalog-Controller-4", err, c)
+	}
+	if !unique {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Equipment %s already exists", equipment.Key))
+	}
+
+	equipment.Created = time.Now().Unix()
+	equipment.Updated = time.Now().Unix()
+	id, err := mongo.CreateEquipment(equipment)
+	if err != nil {
+		return utils.ServerError("Catalog-Controller-5", err, c)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditCreateEquipment,
+		Target: equipment.Key,
+		After:  equipment,
+	})
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"_id":         id,
+	})
+}
+
+// UpdateEquipment updates the display name, unit, category or active flag of an equipment in the catalog
+// Deactivated equipments stay within existing posts and inventories but cannot be used in new ones
+func UpdateEquipment(c *fiber.Ctx) error {
+	update := &types.EquipmentUpdate{}
+	if err := c.BodyParser(update); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	update.Updated = time.Now().Unix()
+	if err := mongo.UpdateEquipment(c.Params("key"), update); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.NewError(fiber.StatusNotFound, "No such equipment exists")
+		}
+		return utils.ServerError("Catalog-Controller-6", err, c)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditUpdateEquipment,
+		Target: utils.ImmutableString(c.Params("key")),
+		After:  update,
+	})
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/invoices"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// fetchClientInvoice returns the invoice given by the "number" parameter if it belongs to the logged in client
+func fetchClientInvoice(c *fiber.Ctx) (*types.Invoice, error) {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return nil, utils.ServerError("Invoice-Controller-1", utils.ErrFailedExtraction, c)
+	}
+	invoice, err := mongo.FetchInvoiceByClient(c.Params("number"), claims.GetEmail())
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Invoice not found")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Invoice-Controller-2", err, c)
+	}
+	return invoice, nil
+}
+
+// FetchInvoicesByClient returns all invoices of a client, latest first
+func FetchInvoicesByClient(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Invoice-Controller-3", utils.ErrFailedExtraction, c)
+	}
+	clientInvoices, err := mongo.FetchInvoicesByClient(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Invoice-Controller-4", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        clientInvoices,
+	})
+}
+
+// FetchInvoiceByClient returns a single invoice of a client
+func FetchInvoiceByClient(c *fiber.Ctx) error {
+	invoice, err := fetchClientInvoice(c)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(invoice)
+}
+
+// RenderInvoiceHTML returns the HTML rendering of an invoice
+func RenderInvoiceHTML(c *fiber.Ctx) error {
+	invoice, err := fetchClientInvoice(c)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := invoices.RenderHTML(&buf, invoice); err != nil {
+		return utils.ServerError("Invoice-Controller-5", err, c)
+	}
+	c.Type("html", "utf-8")
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// RenderInvoicePDF returns the PDF rendering of an invoice as an attachment
+func RenderInvoicePDF(c *fiber.Ctx) error {
+	invoice, err := fetchClientInvoice(c)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := invoices.RenderPDF(&buf, invoice); err != nil {
+		return utils.ServerError("Invoice-Controller-6", err, c)
+	}
+	c.Type("pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"%s.pdf\"", invoice.Number))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sendMessage appends the message in the body to the thread over a vendor's offer on a post and notifies the other party
+// Messages can only be exchanged while the vendor holds either a pending or an accepted offer on the post
+func sendMessage(c *fiber.Ctx, postID, offerKey, vendorEmail string) error {
+	message := &types.Message{}
+	if err := c.BodyParser(message); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := message.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if !message.Quantities.IsEmpty() {
+		if err := validateInventory(c, message.Quantities); err != nil {
+			return err
+		}
+	}
+
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Message-Controller-1", utils.ErrFailedExtraction, c)
+	}
+
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid post ID")
+	}
+	hasOffer, err := mongo.HasOffer(postID, offerKey)
+	if err != nil {
+		return utils.ServerError("Message-Controller-2", err, c)
+	}
+	if !hasOffer {
+		return fiber.NewError(fiber.StatusForbidden, "Messages can only be exchanged over an existing offer on the post")
+	}
+
+	message.ID = primitive.NilObjectID
+	message.PostID = docID
+	message.OfferKey = offerKey
+	message.Vendor = vendorEmail
+	message.Sender = claims.GetEmail()
+	message.SenderRole = claims.Role
+	message.SenderName = claims.GetName()
+	message.Read = 0
+	message.Created = time.Now().Unix()
+
+	id, err := mongo.InsertMessage(message)
+	if err != nil {
+		return utils.ServerError("Message-Controller-3", err, c)
+	}
+
+	postID = utils.ImmutableString(postID)
+	if claims.Role == types.Vendor {
+		// Names are user input, hence they are escaped before being used within the message template
+		name := strings.ReplaceAll(claims.GetName(), "%", "%%")
+		go mongo.NotifyClient(postID, "You have a new message from "+name+" on your post %s")
+	} else {
+		go mongo.NotifyVendorOnMessage(postID, vendorEmail)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"_id":         id,
+	})
+}
+
+// fetchThread returns all messages of the thread over a vendor's offer on a post
+// Messages sent to the reader are marked as read, admins viewing a thread leave it untouched
+func fetchThread(c *fiber.Ctx, postID, offerKey string) ([]types.Message, error) {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return nil, utils.ServerError("Message-Controller-4", utils.ErrFailedExtraction, c)
+	}
+	if claims.Role != types.Admin {
+		if err := mongo.MarkThreadRead(postID, offerKey, claims.Role); err != nil {
+			return nil, utils.ServerError("Message-Controller-5", err, c)
+		}
+	}
+	messages, err := mongo.FetchThread(postID, offerKey)
+	if err != nil {
+		return nil, utils.ServerError("Message-Controller-6", err, c)
+	}
+	return messages, nil
+}
+
+// FetchThreadsByPost returns every thread of a post along with its latest message and the number of unread messages
+func FetchThreadsByPost(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Message-Controller-7", utils.ErrFailedExtraction, c)
+	}
+	threads, err := mongo.FetchThreadsByPost(c.Params("id"), claims.Role)
+	if err != nil {
+		return utils.ServerError("Message-Controller-8", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        threads,
+	})
+}
+
+// FetchThreadByClient returns the thread over the offer given by the "key" parameter on the client's post
+func FetchThreadByClient(c *fiber.Ctx) error {
+	messages, err := fetchThread(c, c.Params("id"), c.Params("key"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        messages,
+	})
+}
+
+// SendMessageByClient sends a message to the vendor of the offer given by the "key" parameter on the client's post
+func SendMessageByClient(c *fiber.Ctx) error {
+	offerKey := utils.ImmutableString(c.Params("key"))
+	vendorEmail, err := utils.Decrypt(offerKey)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid offer key")
+	}
+	return sendMessage(c, c.Params("id"), offerKey, vendorEmail)
+}
+
+// FetchThreadByVendor returns the thread over the vendor's own offer on a post
+func FetchThreadByVendor(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Message-Controller-9", utils.ErrFailedExtraction, c)
+	}
+	offerKey, err := utils.Encrypt(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Message-Controller-10", err, c)
+	}
+	messages, err := fetchThread(c, c.Params("id"), offerKey)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        messages,
+	})
+}
+
+// SendMessageByVendor sends a message to the client over the vendor's own offer on a post
+func SendMessageByVendor(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Message-Controller-11", utils.ErrFailedExtraction, c)
+	}
+	offerKey, err := utils.Encrypt(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Message-Controller-12", err, c)
+	}
+	return sendMessage(c, c.Params("id"), offerKey, claims.GetEmail())
+}
+
+// FetchThreadByAdmin returns the thread over the offer given by the "key" parameter on any post for resolving disputes
+// The email ID of the vendor behind the offer key is revealed as well
+func FetchThreadByAdmin(c *fiber.Ctx) error {
+	offerKey := c.Params("key")
+	vendorEmail, err := utils.Decrypt(offerKey)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid offer key")
+	}
+	messages, err := fetchThread(c, c.Params("id"), offerKey)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"vendor":      vendorEmail,
+		"data":        messages,
+	})
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/payments"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// paymentRequest is the body of requests for paying invoices and paying out vendors
+type paymentRequest struct {
+	// Amount in rupees, defaults to the entire outstanding amount
+	Amount float64 `json:"amount"`
+
+	// Token identifying the client's payment method with the gateway
+	Token string `json:"token"`
+
+	// Email ID of the vendor to be paid out, only used by admins
+	Vendor string `json:"vendor"`
+}
+
+// PayInvoice charges the client through the payment gateway and records the payment against an invoice
+// Partial payments are allowed, the amount cannot exceed the outstanding amount of the invoice
+func PayInvoice(c *fiber.Ctx) error {
+	request := &paymentRequest{}
+	if err := c.BodyParser(request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	invoice, err := fetchClientInvoice(c)
+	if err != nil {
+		return err
+	}
+
+	outstanding := invoice.Outstanding()
+	amount := types.ToPaise(request.Amount)
+	if amount == 0 {
+		amount = outstanding
+	}
+	if outstanding <= 0 {
+		return fiber.NewError(fiber.StatusConflict, "Invoice has already been paid")
+	}
+	if amount < 0 || amount > outstanding {
+		return fiber.NewError(fiber.StatusBadRequest, "Amount should be positive and should not exceed the outstanding amount")
+	}
+
+	gateway, err := payments.Provider()
+	if err != nil {
+		return utils.ServerError("Payment-Controller-1", err, c)
+	}
+	reference, err := gateway.Charge(&payments.Request{
+		Reference: invoice.Number,
+		Email:     invoice.Client,
+		Amount:    amount,
+		Currency:  invoice.Currency,
+		Token:     request.Token,
+	})
+	if err == payments.ErrDeclined {
+		return fiber.NewError(fiber.StatusPaymentRequired, err.Error())
+	}
+	if err != nil {
+		return utils.ServerError("Payment-Controller-2", err, c)
+	}
+
+	if err := mongo.RecordPayment(invoice, amount, reference); err != nil {
+		// The money has been collected but could not be recorded, hence return it to the client
+		if _, refundErr := gateway.Refund(reference, amount); refundErr != nil {
+			utils.LogError("Payment-Controller-3", refundErr)
+		}
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Invoice was paid by another request, the payment has been refunded")
+		}
+		return utils.ServerError("Payment-Controller-4", err, c)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"reference":   reference,
+		"paid":        types.ToRupees(amount),
+		"outstanding": types.ToRupees(outstanding - amount),
+	})
+}
+
+// FetchClientBalance returns the amount owed by the client along with the outstanding amount of every unpaid invoice
+func FetchClientBalance(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Payment-Controller-5", utils.ErrFailedExtraction, c)
+	}
+	balance, err := mongo.FetchAccountBalance(types.ClientAccount(claims.GetEmail()))
+	if err != nil {
+		return utils.ServerError("Payment-Controller-6", err, c)
+	}
+	unpaidInvoices, err := mongo.FetchUnpaidInvoicesByClient(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Payment-Controller-7", err, c)
+	}
+	outstanding := make([]types.M, 0, len(unpaidInvoices))
+	for _, invoice := range unpaidInvoices {
+		outstanding = append(outstanding, types.M{
+			"number":      invoice.Number,
+			"total":       invoice.Total,
+			"paid":        types.ToRupees(invoice.Paid),
+			"outstanding": types.ToRupees(invoice.Outstanding()),
+		})
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"billed":      types.ToRupees(balance.Debit),
+		"paid":        types.ToRupees(balance.Credit),
+		"owed":        types.ToRupees(balance.Balance()),
+		"invoices":    outstanding,
+	})
+}
+
+// FetchVendorBalance returns the amount earned by the vendor, the amount paid out and the amount still owed
+func FetchVendorBalance(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Payment-Controller-8", utils.ErrFailedExtraction, c)
+	}
+	account := types.VendorAccount(claims.GetEmail())
+	balance, err := mongo.FetchAccountBalance(account)
+	if err != nil {
+		return utils.ServerError("Payment-Controller-9", err, c)
+	}
+	journals, err := mongo.FetchJournalsByAccount(account)
+	if err != nil {
+		return utils.ServerError("Payment-Controller-10", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"earned":      types.ToRupees(balance.Credit),
+		"paid_out":    types.ToRupees(balance.Debit),
+		"owed":        types.ToRupees(-balance.Balance()),
+		"data":        journals,
+	})
+}
+
+// FetchLedgerBalances returns the balances of all accounts in the payments ledger in paise
+func FetchLedgerBalances(c *fiber.Ctx) error {
+	balances, err := mongo.FetchAccountBalances()
+	if err != nil {
+		return utils.ServerError("Payment-Controller-11", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        balances,
+	})
+}
+
+// PayoutVendor pays a vendor the amount it is owed through the payment gateway
+// The payout is recorded before the transfer and is reversed if the gateway rejects it
+func PayoutVendor(c *fiber.Ctx) error {
+	request := &paymentRequest{}
+	if err := c.BodyParser(request); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if !validator.IsEmail(request.Vendor) {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'vendor' should be a valid email ID")
+	}
+
+	amount := types.ToPaise(request.Amount)
+	if amount == 0 {
+		payable, err := mongo.FetchPayableBalance(request.Vendor)
+		if err != nil {
+			return utils.ServerError("Payment-Controller-12", err, c)
+		}
+		amount = payable
+	}
+	if amount <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Amount should be positive")
+	}
+
+	gateway, err := payments.Provider()
+	if err != nil {
+		return utils.ServerError("Payment-Controller-13", err, c)
+	}
+
+	payout, err := mongo.RecordPayout(request.Vendor, amount)
+	if err == mongo.ErrInsufficientBalance || err == mongo.ErrUnpaidInvoices {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err == mongo.ErrConflict {
+		return fiber.NewError(fiber.StatusConflict, "Vendor is being paid out by another request, please try again")
+	}
+	if err != nil {
+		return utils.ServerError("Payment-Controller-14", err, c)
+	}
+
+	reference, err := gateway.Payout(&payments.Request{
+		Reference: payout.ID.Hex(),
+		Email:     request.Vendor,
+		Amount:    amount,
+		Currency:  types.Currency,
+		Token:     request.Token,
+	})
+	if err != nil {
+		if reverseErr := mongo.ReversePayout(payout); reverseErr != nil {
+			utils.LogError("Payment-Controller-15", reverseErr)
+		}
+		if err == payments.ErrDeclined {
+			return fiber.NewError(fiber.StatusPaymentRequired, err.Error())
+		}
+		return utils.ServerError("Payment-Controller-16", err, c)
+	}
+	if err := mongo.SetJournalReference(payout.ID, reference); err != nil {
+		utils.LogError("Payment-Controller-17", err)
+	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditPayoutVendor,
+		Target: request.Vendor,
+		After: types.M{
+			"amount":    types.ToRupees(amount),
+			"reference": reference,
+		},
+	})
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"reference":   reference,
+		"paid":        types.ToRupees(amount),
+	})
+}
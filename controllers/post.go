@@ -1,18 +1,22 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"reflect"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	validator "github.com/asaskevich/govalidator"
 	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/configs"
 	"github.com/reverie/models/mongo"
 	"github.com/reverie/sendgrid"
 	"github.com/reverie/types"
 	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CreatePost creates a post requested by a client
@@ -26,12 +30,27 @@ func CreatePost(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if post.EndDate <= post.StartDate {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'end_date' should be after 'start_date'")
+	}
+
+	if post.Requirements.IsEmpty() {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'requirements' should hold atleast one item")
+	}
+	if err := validateInventory(c, post.Requirements); err != nil {
+		return err
+	}
+	if err := post.RequirementSpecs.Validate(post.Requirements); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
 		return utils.ServerError("Post-Controller-1", utils.ErrFailedExtraction, c)
 	}
+	// Initialization only fails on an invalid location
 	if err := post.Initialize(); err != nil {
-		return utils.ServerError("Post-Controller-2", err, c)
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 	post.SetOwner(claims.GetEmail())
 	post.SetOwnerName(claims.GetName())
@@ -39,12 +58,58 @@ func CreatePost(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ServerError("Post-Controller-3", err, c)
 	}
+	post.ID = id.(primitive.ObjectID)
+	go inviteVendors(post)
+	go alertSavedSearches(post, false)
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
 		"_id":         id,
 	})
 }
 
+// inviteVendors invites the vendors based near a new post whose inventory holds atleast one of its required items
+// The best matching vendors are notified and those who opted for it are emailed as well
+func inviteVendors(post *types.Post) {
+	radiusKm := configs.MatchingConfig.RadiusKm
+	if radiusKm <= 0 {
+		radiusKm = types.DefaultMatchingRadiusKm
+	}
+	limit := configs.MatchingConfig.MaxInvitations
+	if limit <= 0 {
+		limit = types.DefaultMaxInvitations
+	}
+	matches, err := mongo.FetchMatchingVendors(post, radiusKm)
+	if err != nil {
+		utils.LogError("Post-Controller-130", err)
+		return
+	}
+	invitations := types.RankVendorMatches(post.Requirements, matches, limit)
+	if err := mongo.InsertInvitations(post, invitations); err != nil {
+		utils.LogError("Post-Controller-131", err)
+		return
+	}
+	for idx := range invitations {
+		if !invitations[idx].Emailed {
+			continue
+		}
+		if err := sendgrid.SendInvitationEmail(post, &invitations[idx]); err != nil {
+			utils.LogError("Mailer-7", err)
+		}
+	}
+}
+
+// FetchInvitations returns the vendors invited to a post, best match first
+func FetchInvitations(c *fiber.Ctx) error {
+	invitations, err := mongo.FetchInvitations(c.Params("id"))
+	if err != nil {
+		return utils.ServerError("Post-Controller-132", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        invitations,
+	})
+}
+
 // FetchActivePostsByClient returns all open/ongoing posts created by a client
 func FetchActivePostsByClient(c *fiber.Ctx) error {
 	claims := utils.ExtractClaims(c)
@@ -73,17 +138,114 @@ func FetchSinglePostByClient(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(post)
 }
 
+// FetchPostCoverage returns per equipment type what is required, accepted, pending and still short on a post
+// along with the cost of the accepted offers
+// The optional "offers" query parameter holds comma separated keys of pending offers which are simulated as accepted
+// so that combinations of offers can be compared before accepting them
+func FetchPostCoverage(c *fiber.Ctx) error {
+	post, err := mongo.FetchSinglePostByClient(c.Params("id"))
+	if err != nil {
+		return utils.ServerError("Post-Controller-126", err, c)
+	}
+	hypothetical := make([]string, 0)
+	for _, offerKey := range strings.Split(c.Query("offers"), ",") {
+		if offerKey = strings.TrimSpace(offerKey); offerKey != types.EMPTY {
+			hypothetical = append(hypothetical, offerKey)
+		}
+	}
+	coverage, err := post.Coverage(hypothetical)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        coverage,
+	})
+}
+
+// RecommendOffers returns the cheapest combination of pending offers which covers the post's remaining requirements,
+// accepting offers partially where they are priced per unit
+// With the optional "budget" query parameter it returns the combination covering the most within the budget instead
+// The recommended offers can be accepted as is through the bundle acceptance
+func RecommendOffers(c *fiber.Ctx) error {
+	budget := math.Inf(1)
+	if value := c.Query("budget"); value != types.EMPTY {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || math.IsInf(parsed, 0) {
+			return fiber.NewError(fiber.StatusBadRequest, "Parameter budget should be a positive number")
+		}
+		budget = parsed
+	}
+	post, err := mongo.FetchSinglePostByClient(c.Params("id"))
+	if err != nil {
+		return utils.ServerError("Post-Controller-127", err, c)
+	}
+	if post.Status != types.OPEN {
+		return fiber.NewError(fiber.StatusForbidden, "Offers can be recommended only on OPEN posts")
+	}
+
+	// Offers are capped at what their vendors can still supply for the post's duration
+	start, end := post.Window()
+	free := make(map[string]types.Inventory)
+	for offerKey := range post.Offers {
+		vendorEmail, err := utils.Decrypt(offerKey)
+		if err != nil {
+			// Offers without any free inventory are never recommended
+			utils.LogError("Post-Controller-128", fmt.Errorf("Could not decrypt offer key %s of post %s: %v", offerKey, post.ID.Hex(), err))
+			continue
+		}
+		vendorInventory, _, _, err := mongo.FetchVendorAvailability(vendorEmail, start, end)
+		if err != nil {
+			return utils.ServerError("Post-Controller-129", err, c)
+		}
+		free[offerKey] = *vendorInventory
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        post.RecommendOffers(free, budget),
+	})
+}
+
 // MakeOffer adds/updates a vendor's offer to a post
+// Every offered item is priced individually in the currency of the offer
 func MakeOffer(c *fiber.Ctx) error {
-	rate, err := strconv.ParseFloat(c.Params("rate"), 64)
-	if err != nil {
-		return utils.ServerError("Post-Controller-7", err, c)
+	input := &types.OfferInput{}
+	if err := c.BodyParser(input); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := input.Prices.Validate(input.Content); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := types.ValidateCurrency(input.Currency); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	return makeOffer(c, input.Content, input.Prices.Subset(input.Content), 0)
+}
 
-	offer := &types.Inventory{}
-	if err := c.BodyParser(offer); err != nil {
+// MakeLegacyOffer adds/updates a vendor's offer to a post priced as a whole at a rate per day
+// Kept for the vendor clients which predate per item pricing
+func MakeLegacyOffer(c *fiber.Ctx) error {
+	rate, err := strconv.ParseFloat(c.Params("rate"), 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return fiber.NewError(fiber.StatusBadRequest, "Rate should be a positive number")
+	}
+	offer := types.Inventory{}
+	if err := c.BodyParser(&offer); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	return makeOffer(c, offer, nil, rate)
+}
+
+// makeOffer validates an offer against the post's requirements and the vendor's free inventory and stores it
+// Offers are either priced per item or, for legacy offers, as a whole at the given rate
+func makeOffer(c *fiber.Ctx, content types.Inventory, prices types.Prices, rate float64) error {
+	offer := &content
+	if offer.IsEmpty() {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer should hold atleast one item")
+	}
+	if err := validateInventory(c, *offer); err != nil {
+		return err
+	}
 
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
@@ -91,53 +253,61 @@ func MakeOffer(c *fiber.Ctx) error {
 	}
 
 	postID := utils.ImmutableString(c.Params("id"))
-	status, requirements, err := mongo.FetchPostRequirementsAndStatus(postID)
-
-	if status != types.OPEN {
-		return fiber.NewError(fiber.StatusForbidden, "Offers can be made only to OPEN posts")
-	}
-
+	post, err := mongo.FetchPostRequirementDetails(postID)
 	if err != nil {
 		return utils.ServerError("Post-Controller-9", err, c)
 	}
 
-	vendorInventory, err := mongo.FetchVendorInventory(claims.GetEmail())
+	if post.Status != types.OPEN {
+		return fiber.NewError(fiber.StatusForbidden, "Offers can be made only to OPEN posts")
+	}
+
+	// Only the inventory which is free throughout the post's duration can be offered
+	start, end := post.Window()
+	vendorInventory, _, _, err := mongo.FetchVendorAvailability(claims.GetEmail(), start, end)
 	if err != nil {
 		return utils.ServerError("Post-Controller-10", err, c)
 	}
 
-	// Validate the offer made by the vendor
-	offerValues := reflect.ValueOf(*offer)
-	requirementValues := reflect.ValueOf(*requirements)
-	vendorInventoryValues := reflect.ValueOf(*vendorInventory)
-
-	for i := 0; i < offerValues.NumField(); i++ {
-		offerVal := offerValues.Field(i).Int()
-		reqVal := requirementValues.Field(i).Int()
-		vendorInvVal := vendorInventoryValues.Field(i).Int()
+	// Check if the offer exceeds the post's requirements or the vendor's inventory
+	if offer.Exceeds(post.Requirements) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
+	}
+	if offer.Exceeds(*vendorInventory) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's free inventory for the post's duration")
+	}
 
-		// Check if fields in the offer are negative or they exceed the post's requirements
-		// or they exceed the vendor's inventory
-		// If yes then return an error
-		if offerVal < 0 {
-			return fiber.NewError(fiber.StatusBadRequest, "Offer values are negative")
-		}
-		if offerVal > reqVal {
-			return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
-		}
-		if offerVal > vendorInvVal {
-			return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's current inventory limits")
-		}
+	// Every offered item needs to meet the specifications required by the client
+	vendorSpecs, err := mongo.FetchVendorInventorySpecs(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Post-Controller-104", err, c)
+	}
+	if err := vendorSpecs.CheckAgainst(post.RequirementSpecs, *offer); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := mongo.UpdatePostOffers(postID, claims.GetEmail(), types.Offer{
-		Name:    claims.GetName(),
-		Created: time.Now().Unix(),
-		Content: *offer,
-		Rate:    rate,
-	}); err != nil {
+	newOffer := types.Offer{
+		Name:     claims.GetName(),
+		Created:  time.Now().Unix(),
+		Content:  *offer,
+		Specs:    vendorSpecs.Subset(*offer),
+		Prices:   prices,
+		Currency: types.Currency,
+	}
+	if rate != 0 {
+		// Legacy offers priced as a whole are quoted in indian rupees per day
+		newOffer.Currency = types.EMPTY
+		newOffer.Rate = rate
+	}
+	previous, err := mongo.UpdatePostOffers(postID, claims.GetEmail(), newOffer)
+	if err != nil {
 		return utils.ServerError("Post-Controller-11", err, c)
 	}
+	var before interface{}
+	if previous != nil {
+		before = *previous
+	}
+	recordOfferAudit(c, types.AuditMakeOffer, postID, claims.GetEmail(), before, newOffer, types.EMPTY)
 
 	go mongo.NotifyClient(postID, claims.GetName()+" made an offer to your post %s")
 
@@ -155,9 +325,13 @@ func RetractOffer(c *fiber.Ctx) error {
 
 	postID := utils.ImmutableString(c.Params("id"))
 
-	if err := mongo.RetractPostOffer(postID, claims.GetEmail()); err != nil {
+	previous, err := mongo.RetractPostOffer(postID, claims.GetEmail())
+	if err != nil {
 		return utils.ServerError("Post-Controller-13", err, c)
 	}
+	if previous != nil {
+		recordOfferAudit(c, types.AuditRetractOffer, postID, claims.GetEmail(), *previous, nil, types.EMPTY)
+	}
 
 	go mongo.NotifyClient(postID, claims.GetName()+" retracted his offer from your post %s")
 
@@ -166,76 +340,204 @@ func RetractOffer(c *fiber.Ctx) error {
 	})
 }
 
-// updatePostStatus updates the status of a post
-func updatePostStatus(c *fiber.Ctx, status string) error {
-	postID := utils.ImmutableString(c.Params("id"))
-	if err := mongo.UpdatePostStatus(postID, status); err != nil {
-		return utils.ServerError("Post-Controller-14", err, c)
+// changePostStatus moves a post into a new status as per the post lifecycle defined in types.IsValidTransition
+// It rejects illegal transitions with a 409, records the transition in the audit log and runs its side effects
+func changePostStatus(c *fiber.Ctx, postID, actor, newStatus, reason string) error {
+	status, err := mongo.FetchPostStatus(postID)
+	if err == mongo.ErrNoDocuments {
+		return fiber.NewError(fiber.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return utils.ServerError("Post-Controller-15", err, c)
 	}
 
+	if !types.IsValidTransition(status, newStatus) {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Post cannot be moved from %s to %s", status, newStatus))
+	}
+
+	// Accepted offers hold reservations on the vendors' inventories irrespective of the post being OPEN or ONGOING
+	// These are released when the post is deleted, completion is handled by completePost
+	if err := mongo.TransitionPostStatus(postID, actor, status, newStatus); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Post status was changed by another request, please try again")
+		}
+		return utils.ServerError("Post-Controller-16", err, c)
+	}
+
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditTransitionPost,
+		Target: postID,
+		Before: status,
+		After:  newStatus,
+		Reason: reason,
+	})
+
 	// Notify all vendors whose offers have been accepted
-	go mongo.BulkNotifyVendors(postID, status)
+	switch {
+	case status == types.COMPLETION_REQUESTED && newStatus == types.ONGOING:
+		go mongo.NotifyAcceptedVendors(postID, "Completion of post %s has been withdrawn, work on it continues")
+	case newStatus == types.ONGOING:
+		go sendPostActivationEmail(postID)
+		go mongo.BulkNotifyVendors(postID, newStatus)
+	default:
+		go mongo.BulkNotifyVendors(postID, newStatus)
+	}
+
+	return nil
+}
 
+// transitionPost moves the post given by the "id" parameter into a new status on behalf of its owner
+func transitionPost(c *fiber.Ctx, newStatus string) error {
+	postID := utils.ImmutableString(c.Params("id"))
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Post-Controller-14", utils.ErrFailedExtraction, c)
+	}
+	if err := changePostStatus(c, postID, claims.GetEmail(), newStatus, types.EMPTY); err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
 	})
 }
 
+// sendPostActivationEmail mails us the details of the post and all parties involved when the post is activated
+func sendPostActivationEmail(postID string) {
+	post, err := mongo.FetchSinglePostByClient(postID)
+	if err != nil {
+		utils.LogError("Mailer-1", err)
+		return
+	}
+	clientEmail := post.Owner
+	emailList := []string{clientEmail}
+	emailToOffer := types.M{
+		clientEmail: types.Inventory{},
+	}
+	emailToPrices := map[string]types.Prices{
+		clientEmail: {},
+	}
+	emailToRate := map[string]float64{
+		clientEmail: 0,
+	}
+	for encryptedEmail, offer := range post.AcceptedOffers {
+		vendorEmail, err := utils.Decrypt(encryptedEmail)
+		if err != nil {
+			utils.LogError("Mailer-2", err)
+			continue
+		}
+		emailList = append(emailList, vendorEmail)
+		emailToOffer[vendorEmail] = offer.Content
+		emailToPrices[vendorEmail] = offer.Prices
+		emailToRate[vendorEmail] = offer.Rate
+	}
+	users, err := mongo.FetchUsers(emailList)
+	if err != nil {
+		utils.LogError("Mailer-3", err)
+		return
+	}
+	for idx, user := range users {
+		if email, ok := user["email"].(string); ok {
+			// For sendgrid template rendering
+			users[idx]["content"] = emailToOffer[email]
+			users[idx]["prices"] = emailToPrices[email]
+			// Only held by legacy offers priced as a whole
+			users[idx]["rate"] = emailToRate[email]
+		}
+	}
+	if err := sendgrid.SendPostActivationEmail(post, users); err != nil {
+		utils.LogError("Mailer-4", err)
+	}
+}
+
 // ActivatePost intiates the post by marking its status as "ONGOING"
 // No new offers can be made to this post
 // This marks the start of the job defined in the post
+// Activating a COMPLETION_REQUESTED post withdraws the completion request
 func ActivatePost(c *fiber.Ctx) error {
-	postID := utils.ImmutableString(c.Params("id"))
-	if err := mongo.UpdatePostStatus(postID, types.ONGOING); err != nil {
-		return utils.ServerError("Post-Controller-15", err, c)
+	return transitionPost(c, types.ONGOING)
+}
+
+// DeactivatePost changes the post status from "ONGOING" to "OPEN"
+// so that the client can accept new offers
+func DeactivatePost(c *fiber.Ctx) error {
+	return transitionPost(c, types.OPEN)
+}
+
+// DeletePost changes the post status to "DELETED"
+// The inventories held by the accepted offers are released back to their vendors
+func DeletePost(c *fiber.Ctx) error {
+	return transitionPost(c, types.DELETED)
+}
+
+// completePost moves a COMPLETION_REQUESTED post to "COMPLETED"
+// The invoice of the post is generated from the duration it was ONGOING and is persisted along with the status change
+// Completions disputed by any vendor can only be approved with a reason
+func completePost(c *fiber.Ctx, postID, actor, reason string) (*types.Invoice, error) {
+	post, err := mongo.FetchSinglePostByClient(postID)
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Post-Controller-108", err, c)
+	}
+
+	if !types.IsValidTransition(post.Status, types.COMPLETED) {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Post cannot be moved from %s to %s", post.Status, types.COMPLETED))
+	}
+	if disputes := post.Disputes(); len(disputes) > 0 && reason == types.EMPTY {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Completion is disputed by %d vendors, a reason is required for overriding the disputes", len(disputes)))
 	}
 
+	billing := configs.BillingConfig
+	invoice := types.NewInvoice(post, time.Now().Unix(), billing.PlatformFeePercent, billing.GSTPercent)
+	if err := mongo.CompletePost(post, actor, invoice); err != nil {
+		if err == mongo.ErrConflict {
+			return nil, fiber.NewError(fiber.StatusConflict, "Post was changed by another request, please try again")
+		}
+		return nil, utils.ServerError("Post-Controller-109", err, c)
+	}
+
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditCompletePost,
+		Target: postID,
+		Before: post.Status,
+		After:  invoice.Number,
+		Reason: reason,
+	})
+
+	go func() {
+		if err := sendgrid.SendPostCompletionEmail(post.Owner, post.OwnerName, invoice); err != nil {
+			utils.LogError("Mailer-0", err)
+		}
+	}()
+
 	// Notify all vendors whose offers have been accepted
-	go mongo.BulkNotifyVendors(postID, types.ONGOING)
+	go mongo.BulkNotifyVendors(postID, types.COMPLETED)
+
+	return invoice, nil
+}
 
+// MarkComplete marks the status of the post as "COMPLETION_REQUESTED"
+// Vendors can then confirm or dispute the completion and we get a mail for approving it
+// The post is COMPLETED along with the generation of its invoice only once an admin approves the completion
+func MarkComplete(c *fiber.Ctx) error {
+	postID := utils.ImmutableString(c.Params("id"))
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
-		return utils.ServerError("Post-Controller-16", utils.ErrFailedExtraction, c)
+		return utils.ServerError("Post-Controller-107", utils.ErrFailedExtraction, c)
+	}
+	if err := changePostStatus(c, postID, claims.GetEmail(), types.COMPLETION_REQUESTED, types.EMPTY); err != nil {
+		return err
 	}
-	clientEmail := claims.GetEmail()
 
 	go func() {
 		post, err := mongo.FetchSinglePostByClient(postID)
 		if err != nil {
-			utils.LogError("Mailer-1", err)
+			utils.LogError("Mailer-5", err)
 			return
 		}
-		emailList := []string{clientEmail}
-		emailToOffer := types.M{
-			clientEmail: types.Inventory{},
-		}
-		emailToRate := map[string]float64{
-			clientEmail: 0,
-		}
-		for encryptedEmail, offer := range post.AcceptedOffers {
-			vendorEmail, err := utils.Decrypt(encryptedEmail)
-			if err != nil {
-				utils.LogError("Mailer-2", err)
-				continue
-			}
-			emailList = append(emailList, vendorEmail)
-			emailToOffer[vendorEmail] = offer.Content
-			emailToRate[vendorEmail] = offer.Rate
-		}
-		users, err := mongo.FetchUsers(emailList)
-		if err != nil {
-			utils.LogError("Mailer-3", err)
-			return
-		}
-		for idx, user := range users {
-			if email, ok := user["email"].(string); ok {
-				// For sendgrid template rendering
-				users[idx]["content"] = emailToOffer[email]
-				users[idx]["rate"] = emailToRate[email]
-			}
-		}
-		if err := sendgrid.SendPostActivationEmail(post, users); err != nil {
-			utils.LogError("Mailer-4", err)
+		if err := sendgrid.SendCompletionRequestEmail(post); err != nil {
+			utils.LogError("Mailer-6", err)
 		}
 	}()
 
@@ -244,55 +546,51 @@ func ActivatePost(c *fiber.Ctx) error {
 	})
 }
 
-// DeactivatePost changes the post status from "ONGOING" to "OPEN"
-// so that the client can accept new offers
-func DeactivatePost(c *fiber.Ctx) error {
-	return updatePostStatus(c, types.OPEN)
-}
-
-// DeletePost changes the post status to "DELETED"
-func DeletePost(c *fiber.Ctx) error {
-	return updatePostStatus(c, types.DELETED)
-}
-
-// MarkComplete marks the status of the post as "COMPLETED"
-// Denotes the end of a job request
-func MarkComplete(c *fiber.Ctx) error {
-	postID := c.Params("id")
-	acceptedOffers, status, postName, lastUpdated, err := mongo.FetchPostAcceptedOffersAndStatusAndName(postID)
-	if err != nil {
-		return utils.ServerError("Post-Controller-100", err, c)
+// respondToCompletion records the vendor's response to the completion requested by the client of the post given by the "id" parameter
+func respondToCompletion(c *fiber.Ctx, confirmed bool) error {
+	response := &types.CompletionResponse{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(response); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
 	}
-
-	if status != types.ONGOING {
-		return fiber.NewError(fiber.StatusForbidden, "Only ONGOING posts can be marked completed")
+	if !confirmed && response.Reason == types.EMPTY {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'reason' is required for disputing the completion")
 	}
+	response.Confirmed = confirmed
+	response.Timestamp = time.Now().Unix()
 
-	if err := mongo.ReleaseVendorInventories(acceptedOffers); err != nil {
-		return utils.ServerError("Post-Controller-101", err, c)
-	}
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
-		return utils.ServerError("Post-Controller-17", utils.ErrFailedExtraction, c)
+		return utils.ServerError("Post-Controller-110", utils.ErrFailedExtraction, c)
+	}
+	postID := utils.ImmutableString(c.Params("id"))
+	if err := mongo.RespondToCompletion(postID, claims.GetEmail(), *response); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Post is not awaiting completion or the vendor's offer was not accepted on it")
+		}
+		return utils.ServerError("Post-Controller-111", err, c)
 	}
 
-	// Amount calculation
-	amount := 0.0
-	for _, offer := range acceptedOffers {
-		amount += offer.Rate // Amount per day
+	message := " has confirmed the completion of your post %s"
+	if !confirmed {
+		message = " has disputed the completion of your post %s"
 	}
-	amount = (float64(time.Now().Unix()-lastUpdated) / (24 * 3600)) * amount // for total duration
+	go mongo.NotifyClient(postID, strings.ReplaceAll(claims.GetName(), "%", "%%")+message)
 
-	// TODO: Uncomment
-	// amount = amount * 1.05 // 5% charge for our services
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
 
-	go func() {
-		if err := sendgrid.SendPostCompletionEmail(claims.GetEmail(), claims.GetName(), postName, amount); err != nil {
-			utils.LogError("Mailer-0", err)
-		}
-	}()
+// ConfirmCompletion lets a vendor whose offer was accepted confirm the completion requested by the client
+func ConfirmCompletion(c *fiber.Ctx) error {
+	return respondToCompletion(c, true)
+}
 
-	return updatePostStatus(c, types.COMPLETED)
+// DisputeCompletion lets a vendor whose offer was accepted dispute the completion requested by the client along with a reason
+func DisputeCompletion(c *fiber.Ctx) error {
+	return respondToCompletion(c, false)
 }
 
 // UpdatePost updates the post by a client
@@ -313,18 +611,80 @@ func UpdatePost(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	// The reservations of the accepted offers are bound to the post's duration
+	if postUpdate.StartDate != 0 || postUpdate.EndDate != 0 {
+		post, err := mongo.FetchSinglePostByClient(postID)
+		if err != nil {
+			return utils.ServerError("Post-Controller-103", err, c)
+		}
+		if len(post.AcceptedOffers) > 0 {
+			return fiber.NewError(fiber.StatusForbidden, "Duration of a post cannot be changed once offers have been accepted")
+		}
+		start, end := post.StartDate, post.EndDate
+		if postUpdate.StartDate != 0 {
+			start = postUpdate.StartDate
+		}
+		if postUpdate.EndDate != 0 {
+			end = postUpdate.EndDate
+		}
+		if end <= start {
+			return fiber.NewError(fiber.StatusBadRequest, "Field 'end_date' should be after 'start_date'")
+		}
+	}
+
+	if postUpdate.Requirements != nil {
+		if err := validateInventory(c, postUpdate.Requirements); err != nil {
+			return err
+		}
+	}
+
+	// Specifications can only be provided for the items which are required
+	if postUpdate.RequirementSpecs != nil {
+		requirements := postUpdate.Requirements
+		if requirements == nil {
+			post, err := mongo.FetchPostRequirementDetails(postID)
+			if err != nil {
+				return utils.ServerError("Post-Controller-105", err, c)
+			}
+			requirements = post.Requirements
+		}
+		if err := postUpdate.RequirementSpecs.Validate(requirements); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
 	if postUpdate.Location != nil {
 		if result, err := validator.ValidateStruct(postUpdate.Location); !result {
 			return fiber.NewError(fiber.StatusBadRequest, err.Error())
 		}
 		if err := postUpdate.InitializeLocation(); err != nil {
-			return utils.ServerError("Post-Controller-18", err, c)
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
 		}
 	}
 
-	if err := mongo.UpdatePost(postID, postUpdate); err != nil {
+	previous, err := mongo.UpdatePost(postID, postUpdate)
+	if err != nil {
 		return utils.ServerError("Post-Controller-19", err, c)
 	}
+	recordAudit(c, &types.AuditEntry{
+		Action: types.AuditUpdatePost,
+		Target: utils.ImmutableString(postID),
+		Before: previous,
+		After:  postUpdate,
+	})
+
+	// Vendors are alerted again only if the post changed in a way which concerns them
+	if postUpdate.ChangesListing(previous) {
+		postID = utils.ImmutableString(postID)
+		go func() {
+			post, err := mongo.FetchSinglePostByClient(postID)
+			if err != nil {
+				utils.LogError("Search-Controller-13", err)
+				return
+			}
+			alertSavedSearches(post, true)
+		}()
+	}
 
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
@@ -347,21 +707,67 @@ func FetchOfferedPostsByVendor(c *fiber.Ctx) error {
 	})
 }
 
-// inventoryCheckSet holds the correct inventory items
-// used for detecting commodities which are not a part of our system ex:- space shuttles :3
-var inventoryCheckSet *types.Set
+// extractGeoQuery extracts the optional geo search parameters "lat", "lng" and "radius_km" from the query
+// Returns nil if no location was provided
+func extractGeoQuery(c *fiber.Ctx) (*types.GeoQuery, error) {
+	lat, lng, radius := c.Query("lat"), c.Query("lng"), c.Query("radius_km")
+	if lat == types.EMPTY && lng == types.EMPTY {
+		if radius != types.EMPTY {
+			return nil, errors.New("Parameters lat and lng are required for searching by distance")
+		}
+		return nil, nil
+	}
+	if !validator.IsLatitude(lat) || !validator.IsLongitude(lng) {
+		return nil, errors.New("Parameters lat and lng should be valid co-ordinates")
+	}
+	geo := &types.GeoQuery{}
+	geo.Latitude, _ = strconv.ParseFloat(lat, 64)
+	geo.Longitude, _ = strconv.ParseFloat(lng, 64)
+	if radius != types.EMPTY {
+		radiusKm, err := strconv.ParseFloat(radius, 64)
+		if err != nil || radiusKm <= 0 {
+			return nil, errors.New("Parameter radius_km should be a positive number")
+		}
+		geo.RadiusKm = radiusKm
+	}
+	return geo, nil
+}
 
-// Initialize the set
-func init() {
-	inventoryCheckSet = types.NewSet()
-	equipments := reflect.TypeOf(types.Inventory{})
-	num := equipments.NumField()
-	for i := 0; i < num; i++ {
-		inventoryCheckSet.Add(equipments.Field(i).Name)
+// extractPostQuery extracts the optional search parameters of open posts from the query
+// along with "min_client_rating" and "sort" which is either updated, distance or client_rating
+func extractPostQuery(c *fiber.Ctx) (*types.PostQuery, error) {
+	geo, err := extractGeoQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	query := &types.PostQuery{
+		Geo:  geo,
+		Sort: c.Query("sort", types.SortByUpdated),
+	}
+	switch query.Sort {
+	case types.SortByUpdated, types.SortByClientRating:
+	case types.SortByDistance:
+		if geo == nil {
+			return nil, errors.New("Parameters lat and lng are required for searching by distance")
+		}
+	default:
+		return nil, fmt.Errorf("%s is an invalid sort parameter, use either updated, distance or client_rating", query.Sort)
+	}
+	if minRating := c.Query("min_client_rating"); minRating != types.EMPTY {
+		rating, err := strconv.ParseFloat(minRating, 64)
+		if err != nil || rating < 1 || rating > 5 {
+			return nil, errors.New("Parameter min_client_rating should be a number between 1 and 5")
+		}
+		query.MinClientRating = rating
 	}
+	return query, nil
 }
 
 // FetchPostsByVendor returns all open posts
+// Posts whose requirement specifications for the lookup items exceed the vendor's specifications are skipped
+// Posts can be restricted to a region with the "lat", "lng" and "radius_km" query parameters
+// in which case each post also holds its distance in kilometres and can be sorted by it with "sort=distance"
+// Posts can also be restricted to clients with an overall rating of at least "min_client_rating" and sorted by it with "sort=client_rating"
 func FetchPostsByVendor(c *fiber.Ctx) error {
 	// Extract page number for pagination and validate
 	page := c.Query("page", "0")
@@ -373,19 +779,33 @@ func FetchPostsByVendor(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Page must be non-negative")
 	}
 
-	// Extract lookup items and validate
+	// Extract lookup items and validate them against the catalog
+	// used for detecting commodities which are not a part of our system ex:- space shuttles :3
+	catalog, err := mongo.FetchEquipmentKeys(false)
+	if err != nil {
+		return utils.ServerError("Post-Controller-43", err, c)
+	}
 	lookupItems := strings.Split(c.Query("items"), ",")
 	for _, item := range lookupItems {
-		if !inventoryCheckSet.Contains(item) {
+		if !catalog.Contains(item) {
 			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s is an invalid lookup item", item))
 		}
 	}
 
+	query, err := extractPostQuery(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
 		return utils.ServerError("Post-Controller-22", utils.ErrFailedExtraction, c)
 	}
-	openPosts, err := mongo.FetchPostsByVendor(claims.GetEmail(), pageNumber, lookupItems)
+	vendorSpecs, err := mongo.FetchVendorInventorySpecs(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Post-Controller-106", err, c)
+	}
+	openPosts, err := mongo.FetchPostsByVendor(claims.GetEmail(), pageNumber, lookupItems, vendorSpecs, query)
 	if err != nil {
 		return utils.ServerError("Post-Controller-23", err, c)
 	}
@@ -432,11 +852,20 @@ func FetchSinglePostByVendor(c *fiber.Ctx) error {
 // This operation is invoked by the client who is the owner of the post
 // The param "offerKey" is key holding the offer in the post
 // It is the vendor's email address encrypted with AES-256
+// The optional body "acceptance" holds the part of the offer being accepted and whether the rest stays pending or is released
+// Only the accepted part is deducted from the post's requirements and reserved from the vendor's inventory
 func AcceptOffer(c *fiber.Ctx) error {
 	postID := utils.ImmutableString(c.Params("id"))
 	offerKey := c.Params("key")
 
-	status, offers, requirements, err := mongo.FetchPostOffersAndRequirementsAndStatus(postID)
+	acceptance := &types.OfferAcceptance{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(acceptance); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	status, offers, requirements, start, end, err := mongo.FetchPostOffersAndRequirementsAndStatusAndWindow(postID)
 	if err != nil {
 		return utils.ServerError("Post-Controller-28", err, c)
 	}
@@ -451,49 +880,197 @@ func AcceptOffer(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Offer key %s doesnt exist in post %s", offerKey, postID))
 	}
 
+	accepted := offer
+	var remainder *types.Offer
+	if len(acceptance.Content) > 0 {
+		accepted, remainder, err = offer.Split(acceptance.Content)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
 	vendorEmail, err := utils.Decrypt(offerKey)
 	if err != nil {
 		return utils.ServerError("Post-Controller-29", err, c)
 	}
 
-	vendorInventory, err := mongo.FetchVendorInventory(vendorEmail)
+	vendorInventory, _, _, err := mongo.FetchVendorAvailability(vendorEmail, start, end)
 	if err != nil {
 		return utils.ServerError("Post-Controller-30", err, c)
 	}
 
-	// Check if offer exceeds post requirements or vendor's current inventory
-	offerValues := reflect.ValueOf(offer.Content)
-	requirementValues := reflect.ValueOf(requirements)
-	vendorInventoryValues := reflect.ValueOf(*vendorInventory)
-
-	sanityChecker := make([]int64, offerValues.NumField())
+	// Check if the accepted part exceeds post requirements or vendor's free inventory for the post's duration
+	if accepted.Content.Exceeds(*vendorInventory) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's free inventory for the post's duration")
+	}
+	if accepted.Content.Exceeds(requirements) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
+	}
 
-	for i := 0; i < offerValues.NumField(); i++ {
-		if offerValues.Field(i).Int() > vendorInventoryValues.Field(i).Int() {
-			fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's current inventory limits")
+	pending := remainder
+	if !acceptance.KeepRemainder {
+		pending = nil
+	}
+	if err := mongo.AcceptOffer(postID, offerKey, vendorEmail, accepted, pending, start, end); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Offer could not be accepted as the offer, the post's requirements or the vendor's inventory changed in the meantime")
+		}
+		if err == mongo.ErrPriceMismatch {
+			return fiber.NewError(fiber.StatusConflict, "Offer is priced differently from the vendor's accepted offer on this post, units accepted earlier cannot be re-priced")
 		}
-		sanityChecker[i] = requirementValues.Field(i).Int() - offerValues.Field(i).Int()
+		return utils.ServerError("Post-Controller-31", err, c)
 	}
+	// The accepted offer is recorded as the outcome so that the audit log shows what the client agreed to
+	recordOfferAudit(c, types.AuditAcceptOffer, postID, vendorEmail, offer, accepted, types.EMPTY)
 
-	for _, check := range sanityChecker {
-		if check < 0 {
-			return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
-		}
+	// Notify vendor
+	if remainder == nil {
+		go mongo.NotifyVendorOnAcceptance(postID, vendorEmail)
+	} else {
+		go mongo.NotifyVendorOnPartialAcceptance(postID, vendorEmail, accepted.Content, remainder.Content, acceptance.KeepRemainder)
 	}
 
-	if err := mongo.AcceptOffer(postID, offerKey, offer); err != nil {
-		return utils.ServerError("Post-Controller-31", err, c)
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// AcceptOfferBundle accepts several offers on a post at once, either all of them are accepted or none
+// The combined accepted content must fit the post's requirements and every part must fit its vendor's free inventory
+// Every offer can be accepted partially just like AcceptOffer, the reasons for refusing the bundle are reported per offer key
+func AcceptOfferBundle(c *fiber.Ctx) error {
+	postID := utils.ImmutableString(c.Params("id"))
+
+	bundle := &types.OfferBundle{}
+	if err := c.BodyParser(bundle); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(bundle.Offers) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Bundle should hold atleast one offer")
 	}
 
-	if err := mongo.UpdateVendorInventoryOnAcceptance(vendorEmail, offer.Content); err != nil {
-		return utils.ServerError("Post-Controller-32", err, c)
+	status, offers, requirements, start, end, err := mongo.FetchPostOffersAndRequirementsAndStatusAndWindow(postID)
+	if err != nil {
+		return utils.ServerError("Post-Controller-122", err, c)
+	}
+	if status != types.OPEN {
+		return fiber.NewError(fiber.StatusForbidden, "Offers can be accepted only on OPEN posts")
 	}
 
-	// Notify vendor
-	go mongo.NotifyVendorOnAcceptance(postID, vendorEmail)
+	reasons := make(map[string]string)
+	items := make([]types.BundledOffer, 0, len(bundle.Offers))
+	combined := make(types.Inventory)
+	for offerKey, acceptance := range bundle.Offers {
+		offer, ok := offers[offerKey]
+		if !ok {
+			reasons[offerKey] = "Offer doesnt exist in the post"
+			continue
+		}
+		accepted := offer
+		var remainder *types.Offer
+		if len(acceptance.Content) > 0 {
+			if accepted, remainder, err = offer.Split(acceptance.Content); err != nil {
+				reasons[offerKey] = err.Error()
+				continue
+			}
+		}
+		vendorEmail, err := utils.Decrypt(offerKey)
+		if err != nil {
+			// The offer can't be attributed to a vendor, the rest of the bundle is still checked
+			utils.LogError("Post-Controller-123", err)
+			reasons[offerKey] = "Offer key could not be decrypted into the vendor's identity"
+			continue
+		}
+		vendorInventory, _, _, err := mongo.FetchVendorAvailability(vendorEmail, start, end)
+		if err != nil {
+			return utils.ServerError("Post-Controller-124", err, c)
+		}
+		if accepted.Content.Exceeds(*vendorInventory) {
+			reasons[offerKey] = "Offer values exceed the vendor's free inventory for the post's duration"
+			continue
+		}
+		if accepted.Content.Exceeds(requirements) {
+			reasons[offerKey] = "Offer values exceed the post's requirements"
+			continue
+		}
+		if !acceptance.KeepRemainder {
+			remainder = nil
+		}
+		combined = combined.Add(accepted.Content)
+		items = append(items, types.BundledOffer{
+			OfferKey:    offerKey,
+			VendorEmail: vendorEmail,
+			Accepted:    accepted,
+			Remainder:   remainder,
+		})
+	}
+
+	// Offers which fit the requirements individually might not fit them together
+	if len(reasons) == 0 {
+		for key, value := range combined {
+			if value <= requirements[key] {
+				continue
+			}
+			for _, item := range items {
+				if item.Accepted.Content[key] > 0 {
+					reasons[item.OfferKey] = fmt.Sprintf("Combined offers hold %d x %s whereas the post requires %d", value, key, requirements[key])
+				}
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(types.M{
+			types.Success: false,
+			types.Error:   "None of the offers were accepted",
+			"reasons":     reasons,
+		})
+	}
+
+	// A fixed order keeps concurrent bundles touching the same vendors from repeatedly conflicting
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].OfferKey < items[j].OfferKey
+	})
+	failed, err := mongo.AcceptOffers(postID, items, start, end)
+	if err == mongo.ErrConflict {
+		response := types.M{
+			types.Success: false,
+			types.Error:   "None of the offers were accepted as the offers, the post's requirements or the vendors' inventories changed in the meantime",
+		}
+		if failed != types.EMPTY {
+			response["reasons"] = map[string]string{
+				failed: "Offer, the post's requirements or the vendor's inventory changed in the meantime",
+			}
+		}
+		return c.Status(fiber.StatusConflict).JSON(response)
+	}
+	if err == mongo.ErrPriceMismatch {
+		return c.Status(fiber.StatusConflict).JSON(types.M{
+			types.Success: false,
+			types.Error:   "None of the offers were accepted as an offer is priced differently from the vendor's accepted offer on this post",
+			"reasons": map[string]string{
+				failed: "Offer is priced differently from the vendor's accepted offer, units accepted earlier cannot be re-priced",
+			},
+		})
+	}
+	if err != nil {
+		return utils.ServerError("Post-Controller-125", err, c)
+	}
+
+	accepted := make([]string, 0, len(items))
+	for _, item := range items {
+		recordOfferAudit(c, types.AuditAcceptOffer, postID, item.VendorEmail, offers[item.OfferKey], item.Accepted, types.EMPTY)
+		if item.Accepted.Content.Equal(offers[item.OfferKey].Content) {
+			go mongo.NotifyVendorOnAcceptance(postID, item.VendorEmail)
+		} else {
+			remaining := offers[item.OfferKey].Content.Subtract(item.Accepted.Content)
+			go mongo.NotifyVendorOnPartialAcceptance(postID, item.VendorEmail, item.Accepted.Content, remaining, item.Remainder != nil)
+		}
+		accepted = append(accepted, item.OfferKey)
+	}
 
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
+		"accepted":    accepted,
 	})
 }
 
@@ -522,13 +1099,17 @@ func RejectAcceptedOffer(c *fiber.Ctx) error {
 		return utils.ServerError("Post-Controller-34", err, c)
 	}
 
-	if err := mongo.RejectAcceptedOffer(postID, offerKey, offer.Content); err != nil {
-		return utils.ServerError("Post-Controller-35", err, c)
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Post-Controller-133", utils.ErrFailedExtraction, c)
 	}
-
-	if err := mongo.ReleaseSingleVendorInventory(vendorEmail, offer.Content); err != nil {
-		return utils.ServerError("Post-Controller-36", err, c)
+	if err := mongo.RejectAcceptedOffer(postID, offerKey, vendorEmail, claims.GetEmail(), offer.Content); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Accepted offer was changed by another request, please try again")
+		}
+		return utils.ServerError("Post-Controller-35", err, c)
 	}
+	recordOfferAudit(c, types.AuditRejectAcceptedOffer, postID, vendorEmail, offer, nil, types.EMPTY)
 
 	// Notify vendor
 	go mongo.NotifyVendorOnRejection(postID, vendorEmail)
@@ -548,9 +1129,13 @@ func RejectPendingOffer(c *fiber.Ctx) error {
 		return utils.ServerError("Post-Controller-37", err, c)
 	}
 
-	if err := mongo.RejectPendingOffer(postID, offerKey); err != nil {
+	previous, err := mongo.RejectPendingOffer(postID, offerKey)
+	if err != nil {
 		return utils.ServerError("Post-Controller-38", err, c)
 	}
+	if previous != nil {
+		recordOfferAudit(c, types.AuditRejectPendingOffer, postID, vendorEmail, *previous, nil, types.EMPTY)
+	}
 
 	// Notify vendor
 	go mongo.NotifyVendorOnRejection(postID, vendorEmail)
@@ -575,8 +1160,11 @@ func RequestOfferChange(c *fiber.Ctx) error {
 	if err := c.BodyParser(offerChange); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	if err := validateInventory(c, *offerChange); err != nil {
+		return err
+	}
 
-	status, offers, requirements, err := mongo.FetchPostOffersAndRequirementsAndStatus(postID)
+	status, offers, requirements, start, end, err := mongo.FetchPostOffersAndRequirementsAndStatusAndWindow(postID)
 	if err != nil {
 		return utils.ServerError("Post-Controller-39", err, c)
 	}
@@ -586,7 +1174,7 @@ func RequestOfferChange(c *fiber.Ctx) error {
 	}
 
 	// Check if offer exists
-	_, ok := offers[offerKey]
+	offer, ok := offers[offerKey]
 	if !ok {
 		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Offer key %s doesnt exist in post %s", offerKey, postID))
 	}
@@ -596,34 +1184,183 @@ func RequestOfferChange(c *fiber.Ctx) error {
 		return utils.ServerError("Post-Controller-40", err, c)
 	}
 
-	vendorInventory, err := mongo.FetchVendorInventory(vendorEmail)
+	vendorInventory, _, _, err := mongo.FetchVendorAvailability(vendorEmail, start, end)
 	if err != nil {
 		return utils.ServerError("Post-Controller-41", err, c)
 	}
 
-	// Check if offer exceeds post requirements or vendor's current inventory
-	offerValues := reflect.ValueOf(offerChange)
-	requirementValues := reflect.ValueOf(requirements)
-	vendorInventoryValues := reflect.ValueOf(*vendorInventory)
+	// Check if offer exceeds post requirements or vendor's free inventory for the post's duration
+	if offerChange.Exceeds(*vendorInventory) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's free inventory for the post's duration")
+	}
+	if offerChange.Exceeds(requirements) {
+		return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
+	}
+
+	if err := mongo.NotifyOfferChangeToVendor(postID, vendorEmail, offerChange); err != nil {
+		return utils.ServerError("Post-Controller-42", err, c)
+	}
+	recordOfferAudit(c, types.AuditRequestOfferChange, postID, vendorEmail, offer.Content, offerChange, types.EMPTY)
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
 
-	sanityChecker := make([]int64, offerValues.NumField())
+// fetchUnresolvedOfferChange returns the change request given by the "id" parameter sent to the current vendor
+func fetchUnresolvedOfferChange(c *fiber.Ctx, vendorEmail string) (*types.Notification, error) {
+	request, err := mongo.FetchOfferChangeRequest(c.Params("id"), vendorEmail)
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "No such change request exists")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Post-Controller-112", err, c)
+	}
+	if request.Resolution != types.EMPTY {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Change request has already been %s", request.Resolution))
+	}
+	return request, nil
+}
 
-	for i := 0; i < offerValues.NumField(); i++ {
-		if offerValues.Field(i).Int() > vendorInventoryValues.Field(i).Int() {
-			fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the vendor's current inventory limits")
+// AcceptOfferChange rewrites the vendor's offer with the contents desired by the client in a change request
+// The desired contents are validated against the post's requirements and the vendor's free inventory just like a new offer
+// The prices of the retained items are carried over, the optional body re-prices items or prices the newly desired ones
+func AcceptOfferChange(c *fiber.Ctx) error {
+	acceptance := &types.OfferChangeAcceptance{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(acceptance); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
 		}
-		sanityChecker[i] = requirementValues.Field(i).Int() - offerValues.Field(i).Int()
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Post-Controller-113", utils.ErrFailedExtraction, c)
+	}
+	request, err := fetchUnresolvedOfferChange(c, claims.GetEmail())
+	if err != nil {
+		return err
+	}
+	if request.DesiredContent == nil || request.DesiredContent.IsEmpty() {
+		return fiber.NewError(fiber.StatusBadRequest, "Change request holds no items, retract the offer instead")
+	}
+	desired := *request.DesiredContent
+	postID := request.PostID.Hex()
+
+	post, err := mongo.FetchPostRequirementDetails(postID)
+	if err != nil {
+		return utils.ServerError("Post-Controller-114", err, c)
+	}
+	if post.Status != types.OPEN {
+		return fiber.NewError(fiber.StatusForbidden, "Offers can be changed only on OPEN posts")
+	}
+
+	// Items might have been removed from the catalog since the change was requested
+	if err := validateInventory(c, desired); err != nil {
+		return err
+	}
+
+	start, end := post.Window()
+	vendorInventory, _, _, err := mongo.FetchVendorAvailability(claims.GetEmail(), start, end)
+	if err != nil {
+		return utils.ServerError("Post-Controller-115", err, c)
+	}
+	if desired.Exceeds(post.Requirements) {
+		return fiber.NewError(fiber.StatusBadRequest, "Desired offer exceeds the post's requirements")
+	}
+	if desired.Exceeds(*vendorInventory) {
+		return fiber.NewError(fiber.StatusBadRequest, "Desired offer exceeds the vendor's free inventory for the post's duration")
+	}
+
+	vendorSpecs, err := mongo.FetchVendorInventorySpecs(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Post-Controller-116", err, c)
+	}
+	if err := vendorSpecs.CheckAgainst(post.RequirementSpecs, desired); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	for _, check := range sanityChecker {
-		if check < 0 {
-			return fiber.NewError(fiber.StatusBadRequest, "Offer values exceed the post's requirements")
+	offerKey, err := utils.Encrypt(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Post-Controller-117", err, c)
+	}
+	_, offers, _, _, _, err := mongo.FetchPostOffersAndRequirementsAndStatusAndWindow(postID)
+	if err != nil {
+		return utils.ServerError("Post-Controller-118", err, c)
+	}
+	current, ok := offers[offerKey]
+	if !ok {
+		return fiber.NewError(fiber.StatusConflict, "Offer no longer exists as it has been accepted, retracted or rejected")
+	}
+
+	offer := types.Offer{
+		Name:     claims.GetName(),
+		Created:  time.Now().Unix(),
+		Content:  desired,
+		Specs:    vendorSpecs.Subset(desired),
+		Currency: types.Currency,
+	}
+	if len(current.Prices) == 0 && len(acceptance.Prices) == 0 {
+		// Legacy offers priced as a whole keep their rate
+		offer.Rate = current.Rate
+		offer.Currency = types.EMPTY
+	} else {
+		prices := current.Prices.Subset(desired)
+		for key, price := range acceptance.Prices {
+			prices[key] = price
 		}
+		if err := prices.Validate(desired); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		offer.Prices = prices
+	}
+	previous, err := mongo.AcceptOfferChange(request, offerKey, offer)
+	if err == mongo.ErrConflict {
+		return fiber.NewError(fiber.StatusConflict, "Change request or the offer was changed by another request, please try again")
+	}
+	if err != nil {
+		return utils.ServerError("Post-Controller-119", err, c)
 	}
+	recordOfferAudit(c, types.AuditAcceptOfferChange, postID, claims.GetEmail(), *previous, offer, types.EMPTY)
 
-	if err := mongo.NotifyOfferChangeToVendor(postID, vendorEmail, offerChange); err != nil {
-		return utils.ServerError("Post-Controller-42", err, c)
+	// Names are user input, hence they are escaped before being used within the message template
+	name := strings.ReplaceAll(claims.GetName(), "%", "%%")
+	go mongo.NotifyClient(postID, name+" accepted the changes you requested on their offer to your post %s")
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// DeclineOfferChange turns down a change request by the client with a reason, the vendor's offer is left untouched
+func DeclineOfferChange(c *fiber.Ctx) error {
+	decline := &types.OfferChangeDecline{}
+	if err := c.BodyParser(decline); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if result, err := validator.ValidateStruct(decline); !result {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Post-Controller-120", utils.ErrFailedExtraction, c)
 	}
+	request, err := fetchUnresolvedOfferChange(c, claims.GetEmail())
+	if err != nil {
+		return err
+	}
+	if err := mongo.DeclineOfferChange(request, decline.Reason); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Change request was changed by another request, please try again")
+		}
+		return utils.ServerError("Post-Controller-121", err, c)
+	}
+	postID := request.PostID.Hex()
+	recordOfferAudit(c, types.AuditDeclineOfferChange, postID, claims.GetEmail(), nil, request.DesiredContent, decline.Reason)
+
+	// The name and reason are user input, hence they are escaped before being used within the message template
+	name := strings.ReplaceAll(claims.GetName(), "%", "%%")
+	reason := strings.ReplaceAll(decline.Reason, "%", "%%")
+	go mongo.NotifyClient(postID, name+" declined the changes you requested on their offer to your post %s: "+reason)
 
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
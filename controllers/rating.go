@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fetchCompletedPost returns the participants of the post given by the "id" parameter
+// Ratings can only be given once the post has been COMPLETED
+func fetchCompletedPost(c *fiber.Ctx) (*types.Post, error) {
+	post, err := mongo.FetchPostParticipants(c.Params("id"))
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Rating-Controller-1", err, c)
+	}
+	if post.Status != types.COMPLETED {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Ratings can only be given after the post is COMPLETED")
+	}
+	return post, nil
+}
+
+// createRating validates the rating in the body of the request and stores it against the ratee
+func createRating(c *fiber.Ctx, post *types.Post, ratee, rateeRole string) error {
+	rating := &types.Rating{}
+	if err := c.BodyParser(rating); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if result, err := validator.ValidateStruct(rating); !result {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Rating-Controller-2", utils.ErrFailedExtraction, c)
+	}
+	rating.ID = primitive.NilObjectID
+	rating.PostID = post.ID
+	rating.Rater = claims.GetEmail()
+	rating.RaterName = claims.GetName()
+	rating.Ratee = ratee
+	rating.RateeRole = rateeRole
+
+	if err := mongo.CreateRating(rating); err != nil {
+		if err == mongo.ErrAlreadyRated {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Rating was changed by another request, please try again")
+		}
+		return utils.ServerError("Rating-Controller-3", err, c)
+	}
+
+	go mongo.NotifyRatee(rating, post.Name)
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// RateVendor lets the client rate a vendor whose offer was accepted on a COMPLETED post
+func RateVendor(c *fiber.Ctx) error {
+	post, err := fetchCompletedPost(c)
+	if err != nil {
+		return err
+	}
+	key := c.Params("key")
+	if _, ok := post.AcceptedOffers[key]; !ok {
+		return fiber.NewError(fiber.StatusNotFound, "Vendor's offer was not accepted on the post")
+	}
+	vendorEmail, err := utils.Decrypt(key)
+	if err != nil {
+		return utils.ServerError("Rating-Controller-4", err, c)
+	}
+	return createRating(c, post, vendorEmail, types.Vendor)
+}
+
+// RateClient lets a vendor whose offer was accepted on a COMPLETED post rate the client
+func RateClient(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Rating-Controller-5", utils.ErrFailedExtraction, c)
+	}
+	post, err := fetchCompletedPost(c)
+	if err != nil {
+		return err
+	}
+	key, err := utils.Encrypt(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Rating-Controller-6", err, c)
+	}
+	if _, ok := post.AcceptedOffers[key]; !ok {
+		return fiber.NewError(fiber.StatusForbidden, "Vendor's offer was not accepted on the post")
+	}
+	return createRating(c, post, post.Owner, types.Client)
+}
+
+// FetchRatingsReceived returns all ratings received by the user along with their aggregate
+func FetchRatingsReceived(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Rating-Controller-7", utils.ErrFailedExtraction, c)
+	}
+	ratings, err := mongo.FetchRatingsByRatee(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Rating-Controller-8", err, c)
+	}
+	user, err := mongo.FetchSingleUserWithoutPassword(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Rating-Controller-9", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"summary":     user.Rating,
+		"data":        ratings,
+	})
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"time"
+
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// parseSavedSearch extracts the saved search in the body and validates its criteria
+func parseSavedSearch(c *fiber.Ctx) (*types.SavedSearch, error) {
+	search := &types.SavedSearch{}
+	if err := c.BodyParser(search); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if result, err := validator.ValidateStruct(search); !result {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	catalog, err := mongo.FetchEquipmentKeys(false)
+	if err != nil {
+		return nil, utils.ServerError("Search-Controller-1", err, c)
+	}
+	if err := search.Validate(catalog); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return search, nil
+}
+
+// CreateSavedSearch saves the search criteria of a vendor
+// The vendor is alerted whenever a new or updated OPEN post matches them
+func CreateSavedSearch(c *fiber.Ctx) error {
+	search, err := parseSavedSearch(c)
+	if err != nil {
+		return err
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Search-Controller-2", utils.ErrFailedExtraction, c)
+	}
+	search.ID = primitive.NilObjectID
+	search.Vendor = claims.GetEmail()
+	search.Created = time.Now().Unix()
+	search.Updated = search.Created
+
+	id, err := mongo.CreateSavedSearch(search)
+	if err != nil {
+		return utils.ServerError("Search-Controller-3", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"_id":         id,
+	})
+}
+
+// FetchSavedSearches returns all searches saved by a vendor
+func FetchSavedSearches(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Search-Controller-4", utils.ErrFailedExtraction, c)
+	}
+	searches, err := mongo.FetchSavedSearches(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Search-Controller-5", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        searches,
+	})
+}
+
+// UpdateSavedSearch replaces the criteria of a vendor's saved search
+func UpdateSavedSearch(c *fiber.Ctx) error {
+	search, err := parseSavedSearch(c)
+	if err != nil {
+		return err
+	}
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Search-Controller-6", utils.ErrFailedExtraction, c)
+	}
+	search.Updated = time.Now().Unix()
+	if err := mongo.UpdateSavedSearch(c.Params("id"), claims.GetEmail(), search); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.NewError(fiber.StatusNotFound, "No such saved search exists")
+		}
+		return utils.ServerError("Search-Controller-7", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// DeleteSavedSearch deletes a vendor's saved search, its pending alerts are dropped from the daily digest
+func DeleteSavedSearch(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Search-Controller-8", utils.ErrFailedExtraction, c)
+	}
+	if err := mongo.DeleteSavedSearch(c.Params("id"), claims.GetEmail()); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fiber.NewError(fiber.StatusNotFound, "No such saved search exists")
+		}
+		return utils.ServerError("Search-Controller-9", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// alertSavedSearches alerts the vendors whose saved searches are matched by a new or updated post
+// Vendors who already hold an offer on the post are skipped
+func alertSavedSearches(post *types.Post, updated bool) {
+	searches, err := mongo.FetchSearchesForPost(post)
+	if err != nil {
+		utils.LogError("Search-Controller-10", err)
+		return
+	}
+	matched := make([]types.SavedSearch, 0)
+	for _, search := range searches {
+		if !search.Matches(post) {
+			continue
+		}
+		offerKey, err := utils.Encrypt(search.Vendor)
+		if err != nil {
+			utils.LogError("Search-Controller-11", err)
+			continue
+		}
+		if _, ok := post.Offers[offerKey]; ok {
+			continue
+		}
+		if _, ok := post.AcceptedOffers[offerKey]; ok {
+			continue
+		}
+		matched = append(matched, search)
+	}
+	if err := mongo.RecordSearchAlerts(post, matched, updated); err != nil {
+		utils.LogError("Search-Controller-12", err)
+	}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	validator "github.com/asaskevich/govalidator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+)
+
+// fetchShipment returns the shipment given by the "shipment" parameter
+func fetchShipment(c *fiber.Ctx) (*types.Shipment, error) {
+	shipment, err := mongo.FetchShipment(c.Params("shipment"))
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Shipment not found")
+	}
+	if err != nil {
+		return nil, utils.ServerError("Shipment-Controller-1", err, c)
+	}
+	return shipment, nil
+}
+
+// FetchShipmentsByPost returns the shipments of all accepted offers of a post
+func FetchShipmentsByPost(c *fiber.Ctx) error {
+	shipments, err := mongo.FetchShipmentsByPost(c.Params("id"))
+	if err != nil {
+		return utils.ServerError("Shipment-Controller-2", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        shipments,
+	})
+}
+
+// ConfirmShipmentReceipt lets the client confirm that a DELIVERED shipment has been received
+func ConfirmShipmentReceipt(c *fiber.Ctx) error {
+	shipment, err := fetchShipment(c)
+	if err != nil {
+		return err
+	}
+	if shipment.PostID.Hex() != c.Params("id") {
+		return fiber.NewError(fiber.StatusNotFound, "Shipment not found")
+	}
+	if shipment.Status != types.DELIVERED {
+		return fiber.NewError(fiber.StatusForbidden, "Only DELIVERED shipments can be confirmed")
+	}
+	if shipment.ReceiptConfirmed != 0 {
+		return fiber.NewError(fiber.StatusConflict, "Receipt of the shipment has already been confirmed")
+	}
+	if err := mongo.ConfirmShipmentReceipt(shipment); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Shipment was changed by another request, please try again")
+		}
+		return utils.ServerError("Shipment-Controller-3", err, c)
+	}
+
+	go mongo.NotifyShipmentUpdate(shipment, shipment.Vendor, "Client has confirmed the receipt of your equipments on post %s")
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
+
+// FetchShipmentsByVendor returns all shipments of a vendor which are yet to be returned
+func FetchShipmentsByVendor(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Shipment-Controller-4", utils.ErrFailedExtraction, c)
+	}
+	shipments, err := mongo.FetchShipmentsByVendor(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("Shipment-Controller-5", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        shipments,
+	})
+}
+
+// UpdateShipment lets a vendor move a shipment along its lifecycle and update its ETA
+// A proof of delivery is required for marking a shipment as DELIVERED
+func UpdateShipment(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("Shipment-Controller-6", utils.ErrFailedExtraction, c)
+	}
+
+	update := &types.ShipmentUpdate{}
+	if err := c.BodyParser(update); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	shipment, err := fetchShipment(c)
+	if err != nil {
+		return err
+	}
+	if shipment.Vendor != claims.GetEmail() {
+		return fiber.NewError(fiber.StatusForbidden, "Vendor is not the shipper of the shipment")
+	}
+
+	if update.Status == types.EMPTY && update.ETA == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Either of the fields 'status' or 'eta' should be provided")
+	}
+	if update.ETA != 0 && update.ETA < time.Now().Unix() {
+		return fiber.NewError(fiber.StatusBadRequest, "Field 'eta' should be in the future")
+	}
+	if update.Status != types.EMPTY && !types.IsValidShipmentTransition(shipment.Status, update.Status) {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Shipment cannot be moved from %s to %s", shipment.Status, update.Status))
+	}
+	if update.Status == types.DELIVERED {
+		if update.ProofOfDelivery == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Field 'proof_of_delivery' is required for marking the shipment as DELIVERED")
+		}
+		if result, err := validator.ValidateStruct(update.ProofOfDelivery); !result {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	} else {
+		update.ProofOfDelivery = nil
+	}
+
+	if err := mongo.UpdateShipment(shipment, claims.GetEmail(), update); err != nil {
+		if err == mongo.ErrConflict {
+			return fiber.NewError(fiber.StatusConflict, "Shipment was changed by another request, please try again")
+		}
+		return utils.ServerError("Shipment-Controller-7", err, c)
+	}
+
+	// The message is used as a template for the post's name
+	vendorName := strings.ReplaceAll(shipment.VendorName, "%", "%%")
+	message := fmt.Sprintf("Estimated delivery of the equipments of %s on post %%s has been updated", vendorName)
+	if update.Status != types.EMPTY {
+		message = fmt.Sprintf("Equipments of %s on post %%s are %s", vendorName, update.Status)
+	}
+	go mongo.NotifyShipmentUpdate(shipment, shipment.Client, message)
+
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"strconv"
 	"time"
 
 	validator "github.com/asaskevich/govalidator"
@@ -25,6 +26,20 @@ func registerUser(c *fiber.Ctx, role string) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	// Ratings can only be received after the completion of posts and only admins can suspend users
+	user.Rating = nil
+	user.Suspended = false
+
+	// Only vendors are matched with the posts created near the base of their fleet
+	if role != types.Vendor {
+		user.Location = nil
+		user.InvitationEmails = false
+	} else if user.Location != nil {
+		if err := user.Location.Initialize(); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
 	unique, err := mongo.IsUniqueEmail(user.GetEmail())
 	if err != nil {
 		return utils.ServerError("User-Controller-1", err, c)
@@ -137,25 +152,192 @@ func UpdatePassword(c *fiber.Ctx) error {
 // 	})
 // }
 
-// InitializeInventory initializes the inventory for a vendor
-// Should be called only once per vendor and this call should be authorized by us
-func InitializeInventory(c *fiber.Ctx) error {
+// inventoryChange is the body of a vendor's request to change its inventory
+type inventoryChange struct {
+	// Inventory is the entire inventory desired by the vendor, items left out are removed from the inventory
+	Inventory types.Inventory `json:"inventory"`
+
+	// Specs of the items to be changed, the specifications of other items are left untouched
+	Specs types.EquipmentSpecs `json:"specs"`
+
+	Note string `json:"note"`
+}
+
+// raiseInventoryRequest stores the inventory change request of the vendor and responds with the requested changes
+// Requests which don't change anything are refused
+func raiseInventoryRequest(c *fiber.Ctx, request *types.InventoryRequest) error {
+	diff := request.Diff(request.Current)
+	specsDiff := request.SpecsDiff(request.CurrentSpecs)
+	if len(diff) == 0 && len(specsDiff) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Requested inventory and specifications are the same as the current ones")
+	}
+	superseded, err := mongo.CreateInventoryRequest(request)
+	if err != nil {
+		return utils.ServerError("User-Controller-25", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"superseded":  superseded,
+		"diff":        diff,
+		"specs_diff":  specsDiff,
+	})
+}
+
+// RequestInventoryChange raises a request to change the vendor's inventory which is applied only once an admin approves it
+// Until then offers are validated against the vendor's current inventory
+// An earlier PENDING request of the vendor is superseded by the new one
+func RequestInventoryChange(c *fiber.Ctx) error {
 	claims := utils.ExtractClaims(c)
 	if claims == nil {
 		return utils.ServerError("User-Controller-12", utils.ErrFailedExtraction, c)
 	}
-	inventory := &types.Inventory{}
-	if err := c.BodyParser(inventory); err != nil {
+	change := &inventoryChange{}
+	if err := c.BodyParser(change); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
-	if err := mongo.InitVendorInventory(claims.GetEmail(), inventory); err != nil {
+	if err := validateInventory(c, change.Inventory); err != nil {
+		return err
+	}
+	if err := change.Specs.Validate(change.Inventory); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	current, err := mongo.FetchVendorInventory(claims.GetEmail())
+	if err != nil {
 		return utils.ServerError("User-Controller-13", err, c)
 	}
+	if current == nil {
+		current = &types.Inventory{}
+	}
+	currentSpecs, err := mongo.FetchVendorInventorySpecs(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("User-Controller-30", err, c)
+	}
+	requested := types.Inventory{}.Add(change.Inventory)
+	request := &types.InventoryRequest{
+		Vendor:       claims.GetEmail(),
+		VendorName:   claims.GetName(),
+		Current:      *current,
+		Requested:    requested,
+		CurrentSpecs: currentSpecs,
+		Note:         change.Note,
+	}
+	if len(change.Specs) > 0 {
+		request.RequestedSpecs = currentSpecs.Merge(change.Specs).Subset(requested)
+	}
+	return raiseInventoryRequest(c, request)
+}
+
+// FetchInventoryRequestsByVendor returns all inventory change requests raised by the vendor
+func FetchInventoryRequestsByVendor(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("User-Controller-26", utils.ErrFailedExtraction, c)
+	}
+	requests, err := mongo.FetchInventoryRequestsByVendor(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("User-Controller-27", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"data":        requests,
+	})
+}
+
+// UpdateMatchingPreferences sets the base location of a vendor's fleet and whether it receives invitations over email
+// Vendors are invited to the new posts near their base whose requirements match their inventory
+func UpdateMatchingPreferences(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("User-Controller-28", utils.ErrFailedExtraction, c)
+	}
+	preferences := &types.MatchingPreferences{}
+	if err := c.BodyParser(preferences); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if result, err := validator.ValidateStruct(preferences); !result {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := preferences.Location.Initialize(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := mongo.UpdateMatchingPreferences(claims.GetEmail(), preferences); err != nil {
+		return utils.ServerError("User-Controller-29", err, c)
+	}
 	return c.Status(fiber.StatusOK).JSON(types.M{
 		types.Success: true,
 	})
 }
 
+// UpdateInventorySpecs raises a request to change the specifications of the items in a vendor's inventory
+// The specifications are applied only once an admin approves the request, until then offers and matching posts
+// are validated against the current specifications
+// Specifications can only be provided for the items present in the inventory
+func UpdateInventorySpecs(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("User-Controller-22", utils.ErrFailedExtraction, c)
+	}
+	specs := types.EquipmentSpecs{}
+	if err := c.BodyParser(&specs); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(specs) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Specifications should be provided for atleast one item")
+	}
+	inventory, err := mongo.FetchVendorInventory(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("User-Controller-23", err, c)
+	}
+	if inventory == nil {
+		return fiber.NewError(fiber.StatusForbidden, "Inventory has not been initialized yet")
+	}
+	if err := specs.Validate(*inventory); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	currentSpecs, err := mongo.FetchVendorInventorySpecs(claims.GetEmail())
+	if err != nil {
+		return utils.ServerError("User-Controller-24", err, c)
+	}
+	return raiseInventoryRequest(c, &types.InventoryRequest{
+		Vendor:         claims.GetEmail(),
+		VendorName:     claims.GetName(),
+		Current:        *inventory,
+		Requested:      types.Inventory{}.Add(*inventory),
+		CurrentSpecs:   currentSpecs,
+		RequestedSpecs: currentSpecs.Merge(specs).Subset(*inventory),
+	})
+}
+
+// FetchVendorAvailability returns the vendor's inventory which is free throughout the time window
+// given by the "start" and "end" query parameters (unix timestamps)
+func FetchVendorAvailability(c *fiber.Ctx) error {
+	claims := utils.ExtractClaims(c)
+	if claims == nil {
+		return utils.ServerError("User-Controller-20", utils.ErrFailedExtraction, c)
+	}
+	start, err := strconv.ParseInt(c.Query("start"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	end, err := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if end <= start {
+		return fiber.NewError(fiber.StatusBadRequest, "Parameter end should be after start")
+	}
+	available, inventory, reserved, err := mongo.FetchVendorAvailability(claims.GetEmail(), start, end)
+	if err != nil {
+		return utils.ServerError("User-Controller-21", err, c)
+	}
+	return c.Status(fiber.StatusOK).JSON(types.M{
+		types.Success: true,
+		"inventory":   inventory,
+		"reserved":    reserved,
+		"available":   available,
+	})
+}
+
 // Login handles the user login process
 func Login(c *fiber.Ctx) error {
 	auth := &types.Login{}
@@ -174,6 +356,10 @@ func Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "User's email is not verified, Please check your email")
 	}
 
+	if user.IsSuspended() {
+		return fiber.NewError(fiber.StatusForbidden, "User's account has been suspended, Please contact support")
+	}
+
 	// Create token
 	token := jwt.New(jwt.SigningMethodHS256)
 
@@ -0,0 +1,52 @@
+package invoices
+
+import (
+	"fmt"
+
+	"github.com/reverie/types"
+)
+
+// formatAmount formats an amount with two decimal places
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// formatEquipment formats the equipment of a line item in the form of "<quantity> x <equipment key>" sorted by the keys
+func formatEquipment(equipment types.Inventory) string {
+	return equipment.Describe()
+}
+
+// unitLabels holds the labels of the units in which equipment is priced
+var unitLabels = map[string]string{
+	types.PerHour: "hour",
+	types.PerDay:  "day",
+	types.PerTrip: "trip",
+}
+
+// formatPrice formats the price of a line item, Ex:- "1200.00/day"
+// Invoices issued before per item pricing only hold the rate per day
+func formatPrice(item types.InvoiceLineItem) string {
+	switch item.Price.Unit {
+	case types.EMPTY:
+		return formatAmount(item.RatePerDay) + "/day"
+	case types.LumpSum:
+		return formatAmount(item.Price.Amount) + " lump sum"
+	}
+	return formatAmount(item.Price.Amount) + "/" + unitLabels[item.Price.Unit]
+}
+
+// formatUnits formats the number of hours, days or trips billed in a line item, Ex:- "3 days"
+func formatUnits(item types.InvoiceLineItem) string {
+	units, unit := item.Units, item.Price.Unit
+	switch unit {
+	case types.EMPTY:
+		units, unit = item.BillableDays, types.PerDay
+	case types.LumpSum:
+		return "-"
+	}
+	label := unitLabels[unit]
+	if units != 1 {
+		label += "s"
+	}
+	return fmt.Sprintf("%d %s", units, label)
+}
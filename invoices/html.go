@@ -0,0 +1,64 @@
+package invoices
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/reverie/types"
+)
+
+// invoiceTemplate is the HTML layout of an invoice, it is kept free of external assets so that it can be rendered offline
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"date":   formatDate,
+	"amount": formatAmount,
+	"items":  formatEquipment,
+	"price":  formatPrice,
+	"units":  formatUnits,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 40px; color: #222; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.amount, th.amount { text-align: right; }
+tfoot td { border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>Issued on {{date .Issued}}</p>
+<p>Billed to <strong>{{.ClientName}}</strong> for <strong>{{.PostName}}</strong></p>
+<p>Billable days : {{.BillableDays}}</p>
+<table>
+<thead>
+<tr><th>Vendor</th><th>Equipment</th><th class="amount">Price</th><th class="amount">Billed</th><th class="amount">Amount</th></tr>
+</thead>
+<tbody>
+{{- range .LineItems}}
+<tr><td>{{.Vendor}}</td><td>{{items .Equipment}}</td><td class="amount">{{price .}}</td><td class="amount">{{units .}}</td><td class="amount">{{amount .Amount}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="4" class="amount">Subtotal</td><td class="amount">{{amount .Subtotal}}</td></tr>
+<tr><td colspan="4" class="amount">Platform fee ({{.PlatformFeePercent}}%)</td><td class="amount">{{amount .PlatformFee}}</td></tr>
+<tr><td colspan="4" class="amount">GST ({{.GSTPercent}}%)</td><td class="amount">{{amount .GST}}</td></tr>
+<tr><td colspan="4" class="amount"><strong>Total ({{.Currency}})</strong></td><td class="amount"><strong>{{amount .Total}}</strong></td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// RenderHTML writes the HTML rendering of an invoice
+func RenderHTML(w io.Writer, invoice *types.Invoice) error {
+	return invoiceTemplate.Execute(w, invoice)
+}
+
+// formatDate formats a unix timestamp as a calendar date
+func formatDate(timestamp int64) string {
+	return time.Unix(timestamp, 0).Format("02 Jan 2006")
+}
@@ -0,0 +1,182 @@
+package invoices
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/reverie/types"
+)
+
+// Layout of the PDF rendering in points, pages are A4 sized
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginLeft   = 50
+	marginTop    = 60
+	fontSize     = 9
+	lineHeight   = 13
+	linesPerPage = (pageHeight - 2*marginTop) / lineHeight
+)
+
+// Widths of the line item columns in characters, a monospaced font is used so that the columns align
+const (
+	vendorWidth    = 20
+	equipmentWidth = 24
+	priceWidth     = 16
+	unitsWidth     = 10
+	amountWidth    = 14
+)
+
+// RenderPDF writes the PDF rendering of an invoice
+// The document is generated by hand using the built-in Courier font so that no external dependencies or assets are needed
+func RenderPDF(w io.Writer, invoice *types.Invoice) error {
+	_, err := w.Write(buildPDF(invoiceLines(invoice)))
+	return err
+}
+
+// invoiceLines lays out an invoice as lines of monospaced text
+func invoiceLines(invoice *types.Invoice) []string {
+	lines := []string{
+		"INVOICE " + invoice.Number,
+		"",
+		"Issued on     : " + formatDate(invoice.Issued),
+		"Billed to     : " + invoice.ClientName,
+		"Post          : " + invoice.PostName,
+		fmt.Sprintf("Billable days : %d", invoice.BillableDays),
+		"",
+		row("Vendor", "Equipment", "Price", "Billed", "Amount"),
+		strings.Repeat("-", vendorWidth+equipmentWidth+priceWidth+unitsWidth+amountWidth+4),
+	}
+	for _, item := range invoice.LineItems {
+		equipment := wrap(formatEquipment(item.Equipment), equipmentWidth)
+		lines = append(lines, row(item.Vendor, equipment[0], formatPrice(item), formatUnits(item), formatAmount(item.Amount)))
+		for _, continuation := range equipment[1:] {
+			lines = append(lines, row("", continuation, "", "", ""))
+		}
+	}
+	lines = append(lines,
+		"",
+		total("Subtotal", invoice.Subtotal),
+		total(fmt.Sprintf("Platform fee (%g%%)", invoice.PlatformFeePercent), invoice.PlatformFee),
+		total(fmt.Sprintf("GST (%g%%)", invoice.GSTPercent), invoice.GST),
+		total(fmt.Sprintf("Total (%s)", invoice.Currency), invoice.Total),
+	)
+	return lines
+}
+
+// row formats a single row of the line items table
+func row(vendor, equipment, price, units, amount string) string {
+	return strings.TrimRight(fmt.Sprintf("%-*s %-*s %*s %*s %*s",
+		vendorWidth, truncate(vendor, vendorWidth),
+		equipmentWidth, equipment,
+		priceWidth, price,
+		unitsWidth, units,
+		amountWidth, amount,
+	), " ")
+}
+
+// total formats a summary row aligned with the amount column
+func total(label string, amount float64) string {
+	labelWidth := vendorWidth + equipmentWidth + priceWidth + unitsWidth + 3
+	return fmt.Sprintf("%*s %*s", labelWidth, label, amountWidth, formatAmount(amount))
+}
+
+// truncate shortens a string to the given number of characters
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "~"
+}
+
+// wrap splits a comma separated list into lines of at most the given width
+func wrap(s string, width int) []string {
+	lines := []string{""}
+	parts := strings.Split(s, ", ")
+	for idx, part := range parts {
+		if idx < len(parts)-1 {
+			part += ","
+		}
+		current := &lines[len(lines)-1]
+		switch {
+		case *current == "":
+			*current = truncate(part, width)
+		case len(*current)+1+len(part) <= width:
+			*current += " " + part
+		default:
+			lines = append(lines, truncate(part, width))
+		}
+	}
+	return lines
+}
+
+// escape escapes a string for usage within a PDF literal string
+// Characters outside printable ASCII are not supported by the standard fonts and are replaced
+func escape(s string) string {
+	var builder strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			builder.WriteRune('\\')
+			builder.WriteRune(r)
+		case r < 32 || r > 126:
+			builder.WriteRune('?')
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// buildPDF generates a PDF document holding the lines of text spread across as many pages as required
+func buildPDF(lines []string) []byte {
+	pages := make([][]string, 0)
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects 1, 2 and 3 are the catalog, the page tree and the font
+	// followed by a content stream and a page object for every page
+	kids := make([]string, 0, len(pages))
+	for idx := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*idx))
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+	for idx, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, lineHeight, marginLeft, pageHeight-marginTop)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", escape(line))
+		}
+		content.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 4+2*idx),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, 0, len(objects))
+	for idx, object := range objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", idx+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
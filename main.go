@@ -2,12 +2,17 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/reverie/configs"
 	"github.com/reverie/utils"
 )
 
 func main() {
+	// Run administrative subcommands instead of the server if provided
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
 	utils.LogInfo("Main-1", "Server running on port %d", configs.Project.Port)
 	newRouter().Listen(fmt.Sprintf(":%d", configs.Project.Port))
 }
@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/reverie/models/mongo"
 	"github.com/reverie/utils"
 )
 
@@ -28,3 +29,32 @@ func IsVendor(c *fiber.Ctx) error {
 	}
 	return fiber.NewError(fiber.StatusForbidden, "User is not a vendor")
 }
+
+// IsAdmin checks whether a user is an admin or not
+func IsAdmin(c *fiber.Ctx) error {
+	user := utils.ExtractClaims(c)
+	if user == nil {
+		return utils.ServerError("Middleware-Validator-5", utils.ErrFailedExtraction, c)
+	}
+	if user.IsAdmin() {
+		return c.Next()
+	}
+	return fiber.NewError(fiber.StatusForbidden, "User is not an admin")
+}
+
+// IsActive checks whether the user's account has been suspended or not
+// Tokens issued before the suspension are rejected as well
+func IsActive(c *fiber.Ctx) error {
+	user := utils.ExtractClaims(c)
+	if user == nil {
+		return utils.ServerError("Middleware-Validator-6", utils.ErrFailedExtraction, c)
+	}
+	suspended, err := mongo.IsUserSuspended(user.GetEmail())
+	if err != nil {
+		return utils.ServerError("Middleware-Validator-7", err, c)
+	}
+	if suspended {
+		return fiber.NewError(fiber.StatusForbidden, "User's account has been suspended, Please contact support")
+	}
+	return c.Next()
+}
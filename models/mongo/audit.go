@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// auditCollectionKey is the collection for the append-only audit log
+	auditCollectionKey = "audit"
+
+	// auditActorKey is the key holding the email ID of the user who performed an action
+	auditActorKey = "actor"
+
+	// auditActionKey is the key holding the action performed
+	auditActionKey = "action"
+
+	// auditTargetKey is the key holding what the action was performed on
+	auditTargetKey = "target"
+
+	// auditVendorKey is the key holding the email ID of the vendor concerned by an offer action
+	auditVendorKey = "vendor"
+
+	// auditBeforeKey is the key holding the value of the target before an action
+	auditBeforeKey = "before"
+
+	// auditPageSize is the maximum number of audit entries retrieved in one batch
+	auditPageSize = 50
+)
+
+var auditCollection = db.Collection(auditCollectionKey)
+
+// InsertAuditEntry appends an entry to the audit log
+// Entries are never updated or deleted
+func InsertAuditEntry(entry *types.AuditEntry) error {
+	_, err := insertOne(auditCollection, entry)
+	return err
+}
+
+// FetchAuditLog returns the audit entries matching the query, latest first
+func FetchAuditLog(query *types.AuditQuery, pageNumber int64) ([]types.M, error) {
+	filter := types.M{}
+	if query.Actor != types.EMPTY {
+		filter[auditActorKey] = query.Actor
+	}
+	if query.Action != types.EMPTY {
+		filter[auditActionKey] = query.Action
+	}
+	if query.Target != types.EMPTY {
+		filter[auditTargetKey] = query.Target
+	}
+	if query.User != types.EMPTY {
+		filter["$or"] = []types.M{
+			{auditActorKey: query.User},
+			{auditTargetKey: query.User},
+			{auditVendorKey: query.User},
+		}
+	}
+	return fetchDocs(auditCollection, filter, options.Find().SetSort(types.M{
+		createdKey: -1,
+	}).SetSkip(auditPageSize*pageNumber).SetLimit(auditPageSize))
+}
+
+// FetchRemovedOffer returns an audit entry recording the removal of an offer along with the removed offer
+// ErrNoDocuments is returned if the entry doesn't exist or doesn't record an offer removal
+func FetchRemovedOffer(entryID string) (*types.AuditEntry, *types.Offer, error) {
+	docID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	raw, err := auditCollection.FindOne(ctx, types.M{
+		primaryKey: docID,
+	}).DecodeBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	entry := &types.AuditEntry{}
+	if err := bson.Unmarshal(raw, entry); err != nil {
+		return nil, nil, err
+	}
+	if !entry.IsOfferRemoval() {
+		return nil, nil, ErrNoDocuments
+	}
+	offer := &types.Offer{}
+	if err := raw.Lookup(auditBeforeKey).Unmarshal(offer); err != nil {
+		return nil, nil, err
+	}
+	return entry, offer, nil
+}
@@ -0,0 +1,206 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// catalogCollectionKey is the collection for all equipment types which can be requested, offered and held in inventories
+	catalogCollectionKey = "catalog"
+
+	// equipmentKey is the key holding the identifier of an equipment
+	equipmentKey = "key"
+
+	// equipmentCategoryKey is the key holding the category of an equipment
+	equipmentCategoryKey = "category"
+
+	// equipmentActiveKey is the key denoting whether an equipment is active or not
+	equipmentActiveKey = "active"
+)
+
+var catalogCollection = db.Collection(catalogCollectionKey)
+
+// seedCatalog inserts the equipments which were hard-coded before the introduction of the catalog
+// Existing entries are left untouched so that changes made by admins are preserved
+func seedCatalog() {
+	operations := make([]mongo.WriteModel, 0)
+	now := time.Now().Unix()
+	for _, equipment := range types.LegacyEquipments {
+		equipment.Created = now
+		equipment.Updated = now
+		operation := mongo.NewUpdateOneModel()
+		operation.SetFilter(types.M{
+			equipmentKey: equipment.Key,
+		})
+		operation.SetUpdate(types.M{
+			"$setOnInsert": equipment,
+		})
+		operation.SetUpsert(true)
+		operations = append(operations, operation)
+	}
+	if _, err := bulkUpsert(catalogCollection, operations); err != nil {
+		utils.LogError("Mongo-Catalog-1", err)
+	}
+}
+
+// FetchCatalog returns all equipments in the catalog
+// Only the active equipments are returned if activeOnly is true
+func FetchCatalog(activeOnly bool) ([]types.M, error) {
+	filter := types.M{}
+	if activeOnly {
+		filter[equipmentActiveKey] = true
+	}
+	return fetchDocs(catalogCollection, filter, options.Find().SetSort(types.M{
+		equipmentCategoryKey: 1,
+		equipmentKey:         1,
+	}))
+}
+
+// FetchEquipmentKeys returns the keys of all equipments in the catalog
+// Only the keys of active equipments are returned if activeOnly is true
+func FetchEquipmentKeys(activeOnly bool) (*types.Set, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	filter := types.M{}
+	if activeOnly {
+		filter[equipmentActiveKey] = true
+	}
+	cursor, err := catalogCollection.Find(ctx, filter, options.Find().SetProjection(types.M{equipmentKey: 1}))
+	if err != nil {
+		return nil, err
+	}
+	equipments := make([]types.Equipment, 0)
+	if err := cursor.All(ctx, &equipments); err != nil {
+		return nil, err
+	}
+	keys := types.NewSet()
+	for _, equipment := range equipments {
+		keys.Add(equipment.Key)
+	}
+	return keys, nil
+}
+
+// IsUniqueEquipmentKey checks if an equipment key is already present in the catalog or not
+func IsUniqueEquipmentKey(key string) (bool, error) {
+	count, err := countDocs(catalogCollection, types.M{equipmentKey: key})
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// CreateEquipment is an abstraction over InsertOne which inserts an equipment into the catalog
+func CreateEquipment(equipment *types.Equipment) (interface{}, error) {
+	return insertOne(catalogCollection, equipment)
+}
+
+// UpdateEquipment updates an equipment in the catalog given its key
+func UpdateEquipment(key string, update *types.EquipmentUpdate) error {
+	return updateOne(catalogCollection, types.M{
+		equipmentKey: key,
+	}, update)
+}
+
+// collectInventoryKeys adds the keys of all items within the inventories to the set
+func collectInventoryKeys(keys *types.Set, inventories ...types.Inventory) {
+	for _, inventory := range inventories {
+		for key := range inventory {
+			keys.Add(key)
+		}
+	}
+}
+
+// MigrateCatalog prepares existing documents for the data-driven catalog
+// It seeds the catalog with the formerly hard-coded equipments and registers any other equipment key found
+// within posts, inventories and reservations as an inactive equipment so that admins can review it
+// Returns the keys which were newly registered
+func MigrateCatalog() ([]string, error) {
+	seedCatalog()
+
+	ctx, cancel := context.WithTimeout(context.Background(), transactionTimeout*time.Second)
+	defer cancel()
+
+	keys := types.NewSet()
+
+	cursor, err := postCollection.Find(ctx, types.M{}, options.Find().SetProjection(types.M{
+		postRequirementsKey:   1,
+		postOffersKey:         1,
+		postAcceptedOffersKey: 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]types.Post, 0)
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		collectInventoryKeys(keys, post.Requirements)
+		for _, offer := range post.Offers {
+			collectInventoryKeys(keys, offer.Content)
+		}
+		for _, offer := range post.AcceptedOffers {
+			collectInventoryKeys(keys, offer.Content)
+		}
+	}
+
+	cursor, err = userCollection.Find(ctx, types.M{
+		userInventoryKey: types.M{
+			"$exists": true,
+		},
+	}, options.Find().SetProjection(types.M{userInventoryKey: 1}))
+	if err != nil {
+		return nil, err
+	}
+	users := make([]types.User, 0)
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Inventory != nil {
+			collectInventoryKeys(keys, *user.Inventory)
+		}
+	}
+
+	cursor, err = reservationCollection.Find(ctx, types.M{})
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]types.Reservation, 0)
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	for _, reservation := range reservations {
+		collectInventoryKeys(keys, reservation.Content)
+	}
+
+	registered := make([]string, 0)
+	now := time.Now().Unix()
+	for _, key := range keys.Values() {
+		unique, err := IsUniqueEquipmentKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if !unique {
+			continue
+		}
+		if _, err := CreateEquipment(&types.Equipment{
+			Key:     key,
+			Name:    key,
+			Unit:    "unit",
+			Active:  false,
+			Created: now,
+			Updated: now,
+		}); err != nil {
+			return nil, err
+		}
+		registered = append(registered, key)
+	}
+	return registered, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/reverie/utils"
 
 	"github.com/reverie/configs"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -35,9 +36,9 @@ func setupAdmin() {
 		OfficeAddress: "Rourkela",
 		Verified:      true,
 		Inventory: &types.Inventory{
-			Truck:      100,
-			Crane:      100,
-			BoomLifter: 100,
+			"Truck":      100,
+			"Crane":      100,
+			"BoomLifter": 100,
 		},
 	}
 	filter := types.M{userEmailKey: adminInfo.Email}
@@ -66,6 +67,247 @@ func createGeoIndex() {
 	}
 }
 
+func createReservationIndex() {
+	index := mongo.IndexModel{
+		Keys: types.M{
+			reservationVendorKey: 1,
+			reservationEndKey:    1,
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := reservationCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-8", err)
+	}
+}
+
+func createCatalogIndex() {
+	index := mongo.IndexModel{
+		Keys: types.M{
+			equipmentKey: 1,
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := catalogCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-9", err)
+	}
+}
+
+func createInvoiceIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: types.M{
+				invoiceNumberKey: 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// A post can be billed only once
+			Keys: types.M{
+				invoicePostIDKey: 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: invoiceClientKey, Value: 1},
+				{Key: invoiceSequenceKey, Value: -1},
+			},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := invoiceCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-10", err)
+	}
+}
+
+func createLedgerIndex() {
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: concat(journalPostingsKey, postingAccountKey), Value: 1},
+			{Key: createdKey, Value: -1},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := ledgerCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-11", err)
+	}
+}
+
+func createShipmentIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			// An accepted offer is shipped only once
+			Keys: bson.D{
+				{Key: shipmentPostIDKey, Value: 1},
+				{Key: shipmentOfferKey, Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: shipmentVendorKey, Value: 1},
+				{Key: updatedKey, Value: -1},
+			},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := shipmentCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-12", err)
+	}
+}
+
+func createRatingIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			// A user can rate another user only once for a post
+			Keys: bson.D{
+				{Key: ratingPostIDKey, Value: 1},
+				{Key: ratingRaterKey, Value: 1},
+				{Key: ratingRateeKey, Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: ratingRateeKey, Value: 1},
+				{Key: createdKey, Value: -1},
+			},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := ratingCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-13", err)
+	}
+}
+
+func createAuditIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: auditTargetKey, Value: 1},
+				{Key: createdKey, Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: auditActorKey, Value: 1},
+				{Key: createdKey, Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: auditVendorKey, Value: 1},
+				{Key: createdKey, Value: -1},
+			},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := auditCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-14", err)
+	}
+}
+
+func createMessageIndex() {
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: messagePostIDKey, Value: 1},
+			{Key: messageOfferKey, Value: 1},
+			{Key: createdKey, Value: 1},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := messageCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-16", err)
+	}
+}
+
+func createInvitationIndex() {
+	// $geoNear on the base locations of vendors' fleets requires a 2dsphere index
+	index := mongo.IndexModel{
+		Keys: types.M{
+			userLocationKey: "2dsphere",
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := userCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-17", err)
+	}
+	index = mongo.IndexModel{
+		Keys: bson.D{
+			{Key: invitationPostIDKey, Value: 1},
+			{Key: invitationRankKey, Value: 1},
+		},
+	}
+	if _, err := invitationCollection.Indexes().CreateOne(ctx, index, opts); err != nil {
+		utils.LogError("Mongo-Connection-18", err)
+	}
+}
+
+func createSearchIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: searchItemsKey, Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: searchVendorKey, Value: 1},
+				{Key: createdKey, Value: -1},
+			},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := searchCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-19", err)
+	}
+	indexes = []mongo.IndexModel{
+		{
+			// A post raises a single alert per saved search
+			Keys: bson.D{
+				{Key: alertSearchIDKey, Value: 1},
+				{Key: alertPostIDKey, Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: alertDigestKey, Value: 1},
+				{Key: alertVendorKey, Value: 1},
+			},
+		},
+	}
+	if _, err := alertCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-20", err)
+	}
+}
+
+func createInventoryRequestIndex() {
+	indexes := []mongo.IndexModel{
+		{
+			// A vendor can only have a single PENDING request
+			Keys: bson.D{
+				{Key: inventoryRequestVendorKey, Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(types.M{
+				inventoryRequestStatusKey: types.PENDING,
+			}),
+		},
+		{
+			Keys: bson.D{
+				{Key: inventoryRequestStatusKey, Value: 1},
+				{Key: createdKey, Value: 1},
+			},
+		},
+	}
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+	if _, err := inventoryRequestCollection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		utils.LogError("Mongo-Connection-15", err)
+	}
+}
+
 func setup() {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -79,6 +321,18 @@ func setup() {
 		utils.LogInfo("Mongo-Connection-6", "MongoDB Connection Established")
 		setupAdmin()
 		createGeoIndex()
+		createReservationIndex()
+		createCatalogIndex()
+		createInvoiceIndex()
+		createLedgerIndex()
+		createShipmentIndex()
+		createRatingIndex()
+		createAuditIndex()
+		createInventoryRequestIndex()
+		createMessageIndex()
+		createInvitationIndex()
+		createSearchIndex()
+		seedCatalog()
 	}
 }
 
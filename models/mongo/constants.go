@@ -1,6 +1,8 @@
 package mongo
 
 import (
+	"errors"
+
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -13,8 +15,19 @@ const (
 
 	// timeout is the context timeout for generic operations
 	timeout = 5
+
+	// transactionTimeout is the context timeout for multi-document transactions including their retries
+	transactionTimeout = 15
 )
 
 // ErrNoDocuments is the error when no matching documents are found
 // for an update operation
 var ErrNoDocuments = mongo.ErrNoDocuments
+
+// ErrConflict is the error when a conditional update fails because the document
+// was modified by another request in the meantime
+var ErrConflict = errors.New("Document was modified by another request")
+
+// ErrPriceMismatch is the error when an offer cannot be merged into the vendor's accepted offer on a post
+// as it quotes different prices for the items accepted earlier
+var ErrPriceMismatch = errors.New("Offer is priced differently from the vendor's accepted offer")
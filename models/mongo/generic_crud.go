@@ -48,6 +48,19 @@ func fetchDocs(collection *mongo.Collection, filter types.M, opts ...*options.Fi
 	return data, err
 }
 
+// aggregate runs an aggregation pipeline on a mongoDB collection and returns the resulting documents
+func aggregate(collection *mongo.Collection, pipeline []types.M, opts ...*options.AggregateOptions) ([]types.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	var data []types.M
+	cursor, err := collection.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(ctx, &data)
+	return data, err
+}
+
 // countDocs returns the number of documents matching a filter
 func countDocs(collection *mongo.Collection, filter types.M) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
@@ -86,3 +99,10 @@ func deleteOne(collection *mongo.Collection, filter types.M) (interface{}, error
 	defer cancel()
 	return collection.DeleteOne(ctx, filter)
 }
+
+// deleteMany deletes multiple documents from a mongoDB collection
+func deleteMany(collection *mongo.Collection, filter types.M) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return collection.DeleteMany(ctx, filter)
+}
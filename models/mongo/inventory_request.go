@@ -0,0 +1,143 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// inventoryRequestCollectionKey is the collection for the vendors' requests to change their inventories
+	inventoryRequestCollectionKey = "inventory_requests"
+
+	// inventoryRequestVendorKey is the key holding the email ID of the vendor who raised a request
+	inventoryRequestVendorKey = "vendor"
+
+	// inventoryRequestStatusKey is the key holding the status of a request
+	inventoryRequestStatusKey = "status"
+
+	// inventoryRequestReviewerKey is the key holding the email ID of the admin who reviewed a request
+	inventoryRequestReviewerKey = "reviewer"
+
+	// inventoryRequestReasonKey is the key holding the admin's reason for approving or rejecting a request
+	inventoryRequestReasonKey = "review_reason"
+
+	// inventoryRequestReviewedKey is the key holding the timestamp at which a request was reviewed
+	inventoryRequestReviewedKey = "reviewed"
+
+	// inventoryRequestPageSize is the maximum number of requests retrieved in one batch for admins
+	inventoryRequestPageSize = 30
+)
+
+var inventoryRequestCollection = db.Collection(inventoryRequestCollectionKey)
+
+// CreateInventoryRequest stores a vendor's request to change its inventory or the specifications of its items
+// A vendor can only have a single PENDING request, hence an existing one is superseded by the new request
+// Returns true if an existing request was superseded
+func CreateInventoryRequest(request *types.InventoryRequest) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	request.Status = types.PENDING
+	request.Created = time.Now().Unix()
+	res, err := inventoryRequestCollection.ReplaceOne(ctx, types.M{
+		inventoryRequestVendorKey: request.Vendor,
+		inventoryRequestStatusKey: types.PENDING,
+	}, request, options.Replace().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// FetchInventoryRequestsByVendor returns all inventory change requests of a vendor, latest first
+func FetchInventoryRequestsByVendor(vendorEmail string) ([]types.M, error) {
+	return fetchDocs(inventoryRequestCollection, types.M{
+		inventoryRequestVendorKey: vendorEmail,
+	}, options.Find().SetSort(types.M{
+		createdKey: -1,
+	}).SetProjection(types.M{
+		inventoryRequestReviewerKey: 0,
+	}))
+}
+
+// FetchInventoryRequests returns the inventory change requests with the given status, oldest first
+func FetchInventoryRequests(status string, pageNumber int64) ([]types.InventoryRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cursor, err := inventoryRequestCollection.Find(ctx, types.M{
+		inventoryRequestStatusKey: status,
+	}, options.Find().SetSort(types.M{
+		createdKey: 1,
+	}).SetSkip(inventoryRequestPageSize*pageNumber).SetLimit(inventoryRequestPageSize))
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]types.InventoryRequest, 0)
+	err = cursor.All(ctx, &requests)
+	return requests, err
+}
+
+// FetchInventoryRequest returns a single inventory change request given its id
+func FetchInventoryRequest(requestID string) (*types.InventoryRequest, error) {
+	docID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	request := &types.InventoryRequest{}
+	err = inventoryRequestCollection.FindOne(ctx, types.M{
+		primaryKey: docID,
+	}).Decode(request)
+	return request, err
+}
+
+// reviewInventoryRequest moves a PENDING request to the given status
+// ErrConflict is returned if the request has already been reviewed or superseded in the meantime
+func reviewInventoryRequest(ctx context.Context, request *types.InventoryRequest, status, reviewer, reason string) error {
+	request.Status = status
+	request.Reviewer = reviewer
+	request.ReviewReason = reason
+	request.Reviewed = time.Now().Unix()
+	return conflictOnNoDocuments(inventoryRequestCollection.FindOneAndUpdate(ctx, types.M{
+		primaryKey:                request.ID,
+		inventoryRequestStatusKey: types.PENDING,
+		createdKey:                request.Created,
+	}, types.M{
+		"$set": types.M{
+			inventoryRequestStatusKey:   request.Status,
+			inventoryRequestReviewerKey: request.Reviewer,
+			inventoryRequestReasonKey:   request.ReviewReason,
+			inventoryRequestReviewedKey: request.Reviewed,
+		},
+	}).Err())
+}
+
+// ApproveInventoryRequest applies a PENDING request to the vendor's inventory and specifications and returns the previous inventory
+// ErrInventoryReserved is returned if the requested inventory falls below the amount reserved for accepted offers
+func ApproveInventoryRequest(request *types.InventoryRequest, reviewer, reason string) (*types.Inventory, error) {
+	var previous *types.Inventory
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		if err := reviewInventoryRequest(ctx, request, types.APPROVED, reviewer, reason); err != nil {
+			return err
+		}
+		var err error
+		previous, err = replaceVendorInventory(ctx, request.Vendor, request.Requested, request.RequestedSpecs)
+		return err
+	})
+	return previous, err
+}
+
+// RejectInventoryRequest turns down a PENDING request leaving the vendor's inventory untouched
+func RejectInventoryRequest(request *types.InventoryRequest, reviewer, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return reviewInventoryRequest(ctx, request, types.REJECTED, reviewer, reason)
+}
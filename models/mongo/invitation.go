@@ -0,0 +1,156 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// invitationCollectionKey is the collection for the invitations sent to vendors matching new posts
+	invitationCollectionKey = "invitations"
+
+	// invitationPostIDKey is the key holding the ID of the post a vendor has been invited to
+	invitationPostIDKey = "post_id"
+
+	// invitationRankKey is the key holding the rank of an invited vendor among all the vendors invited to a post
+	invitationRankKey = "rank"
+
+	// userLocationKey is the key holding the base location of a vendor's fleet
+	userLocationKey = "location"
+
+	// userInvitationEmailsKey is the key denoting whether a vendor receives its invitations over email as well
+	userInvitationEmailsKey = "invitation_emails"
+
+	// vendorDistanceKey is the key holding the distance (in kilometres) of a vendor from a post in vendor matching
+	vendorDistanceKey = "distance_km"
+)
+
+var invitationCollection = db.Collection(invitationCollectionKey)
+
+// UpdateMatchingPreferences updates the base location of a vendor's fleet and whether it receives invitations over email
+func UpdateMatchingPreferences(vendorEmail string, preferences *types.MatchingPreferences) error {
+	filter := types.M{
+		userEmailKey: vendorEmail,
+		userRoleKey:  types.Vendor,
+	}
+	return updateOne(userCollection, filter, types.M{
+		userLocationKey:         preferences.Location,
+		userInvitationEmailsKey: preferences.InvitationEmails,
+	})
+}
+
+// FetchMatchingVendors returns the verified vendors based within radiusKm of a post
+// whose inventory holds atleast one of the post's required items, nearest first
+// The inventory of every match is replaced by its part which is free throughout the post's window
+func FetchMatchingVendors(post *types.Post, radiusKm float64) ([]types.VendorMatch, error) {
+	items := make([]types.M, 0, len(post.Requirements))
+	for key, value := range post.Requirements {
+		if value > 0 {
+			items = append(items, types.M{
+				concat(userInventoryKey, key): types.M{"$gt": 0},
+			})
+		}
+	}
+	matches := make([]types.VendorMatch, 0)
+	if len(items) == 0 {
+		return matches, nil
+	}
+	filter := types.M{
+		userRoleKey:      types.Vendor,
+		userVerifiedKey:  true,
+		userSuspendedKey: types.M{"$ne": true},
+		"$or":            items,
+	}
+
+	// $geoNear makes use of the 2dsphere index on the vendor's location and has to be the first stage of the pipeline
+	pipeline := []types.M{
+		{"$geoNear": types.M{
+			"near": types.M{
+				"type":        "Point",
+				"coordinates": post.Location.Coordinates,
+			},
+			"key":                userLocationKey,
+			"spherical":          true,
+			"query":              filter,
+			"distanceField":      vendorDistanceKey,
+			"distanceMultiplier": 1.0 / metresPerKm,
+			"maxDistance":        radiusKm * metresPerKm,
+		}},
+		{"$project": types.M{
+			userEmailKey:            1,
+			usernameKey:             1,
+			userInventoryKey:        1,
+			userRatingKey:           1,
+			userInvitationEmailsKey: 1,
+			vendorDistanceKey:       1,
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	cursor, err := userCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+
+	// Vendors whose matching items are booked for the post's window are dropped while ranking
+	start, end := post.Window()
+	for idx := range matches {
+		available, _, _, err := FetchVendorAvailability(matches[idx].Email, start, end)
+		if err != nil {
+			return nil, err
+		}
+		matches[idx].Inventory = *available
+	}
+	return matches, nil
+}
+
+// InsertInvitations stores the invitations sent for a post and notifies the invited vendors
+func InsertInvitations(post *types.Post, invitations []types.Invitation) error {
+	if len(invitations) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	docs := make([]interface{}, 0, len(invitations))
+	notifications := make([]interface{}, 0, len(invitations))
+	for idx := range invitations {
+		invitations[idx].PostID = post.ID
+		invitations[idx].Created = now
+		docs = append(docs, invitations[idx])
+		notifications = append(notifications, types.Notification{
+			PostID:   post.ID,
+			Recipent: invitations[idx].Vendor,
+			Type:     types.INFO,
+			Message: fmt.Sprintf("A new job %s matching your fleet has been posted %.1f km away, your fleet can supply %s",
+				post.Name, invitations[idx].DistanceKm, invitations[idx].Matched.Describe()),
+			Read:    false,
+			Created: now,
+		})
+	}
+	if _, err := insertMany(invitationCollection, docs); err != nil {
+		return err
+	}
+	_, err := insertMany(notificationCollection, notifications)
+	return err
+}
+
+// FetchInvitations returns the vendors invited to a post, best match first
+func FetchInvitations(postID string) ([]types.M, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+	return fetchDocs(invitationCollection, types.M{
+		invitationPostIDKey: docID,
+	}, options.Find().SetSort(types.M{
+		invitationRankKey: 1,
+	}))
+}
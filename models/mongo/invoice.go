@@ -0,0 +1,127 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/reverie/configs"
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// invoiceCollectionKey is the collection for all invoices generated on completion of posts
+	invoiceCollectionKey = "invoices"
+
+	// counterCollectionKey is the collection holding the sequences used for generating sequential identifiers
+	counterCollectionKey = "counters"
+
+	// invoiceCounterID is the ID of the counter used for generating invoice numbers
+	invoiceCounterID = "invoice"
+
+	// counterSequenceKey is the key holding the last value of a sequence
+	counterSequenceKey = "sequence"
+
+	// invoiceNumberKey is the key holding the human readable number of an invoice
+	invoiceNumberKey = "number"
+
+	// invoiceSequenceKey is the key holding the sequence of an invoice
+	invoiceSequenceKey = "sequence"
+
+	// invoicePostIDKey is the key holding the ID of the post an invoice belongs to
+	invoicePostIDKey = "post_id"
+
+	// invoiceClientKey is the key holding the email ID of the client who is billed
+	invoiceClientKey = "client"
+
+	// defaultInvoicePrefix is used for invoice numbers when no prefix is configured
+	defaultInvoicePrefix = "INV"
+)
+
+var (
+	invoiceCollection = db.Collection(invoiceCollectionKey)
+	counterCollection = db.Collection(counterCollectionKey)
+)
+
+// nextSequence increments a counter and returns its new value
+// Incrementing within a transaction makes concurrent transactions conflict instead of skipping or reusing values
+func nextSequence(ctx context.Context, counterID string) (int64, error) {
+	counter := struct {
+		Sequence int64 `bson:"sequence"`
+	}{}
+	err := counterCollection.FindOneAndUpdate(ctx, types.M{
+		primaryKey: counterID,
+	}, types.M{
+		"$inc": types.M{
+			counterSequenceKey: 1,
+		},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	return counter.Sequence, err
+}
+
+// insertInvoice assigns the next invoice number to an invoice and persists it
+func insertInvoice(ctx context.Context, invoice *types.Invoice) error {
+	sequence, err := nextSequence(ctx, invoiceCounterID)
+	if err != nil {
+		return err
+	}
+	prefix := configs.BillingConfig.InvoicePrefix
+	if prefix == types.EMPTY {
+		prefix = defaultInvoicePrefix
+	}
+	invoice.SetSequence(prefix, sequence)
+	_, err = invoiceCollection.InsertOne(ctx, invoice)
+	return err
+}
+
+// FetchInvoicesByClient returns all invoices of a client, latest first
+func FetchInvoicesByClient(clientEmail string) ([]types.M, error) {
+	return fetchDocs(invoiceCollection, types.M{
+		invoiceClientKey: clientEmail,
+	}, options.Find().SetSort(types.M{
+		invoiceSequenceKey: -1,
+	}).SetProjection(types.M{
+		invoiceClientKey:   0,
+		invoiceSequenceKey: 0,
+		invoicePaidKey:     0,
+	}))
+}
+
+// FetchInvoiceByClient returns a single invoice of a client given its number
+func FetchInvoiceByClient(number, clientEmail string) (*types.Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	invoice := &types.Invoice{}
+	err := invoiceCollection.FindOne(ctx, types.M{
+		invoiceNumberKey: number,
+		invoiceClientKey: clientEmail,
+	}).Decode(invoice)
+	return invoice, err
+}
+
+// FetchUnpaidInvoicesByClient returns all invoices of a client which are yet to be paid completely, oldest first
+func FetchUnpaidInvoicesByClient(clientEmail string) ([]types.Invoice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cursor, err := invoiceCollection.Find(ctx, types.M{
+		invoiceClientKey: clientEmail,
+	}, options.Find().SetSort(types.M{
+		invoiceSequenceKey: 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	clientInvoices := make([]types.Invoice, 0)
+	if err := cursor.All(ctx, &clientInvoices); err != nil {
+		return nil, err
+	}
+	unpaidInvoices := make([]types.Invoice, 0)
+	for _, invoice := range clientInvoices {
+		if invoice.Outstanding() > 0 {
+			unpaidInvoices = append(unpaidInvoices, invoice)
+		}
+	}
+	return unpaidInvoices, nil
+}
@@ -0,0 +1,298 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ledgerCollectionKey is the collection holding the journals of the double-entry payments ledger
+	ledgerCollectionKey = "ledger"
+
+	// journalPostingsKey is the key holding the postings of a journal
+	journalPostingsKey = "postings"
+
+	// postingAccountKey is the key holding the account of a posting
+	postingAccountKey = "account"
+
+	// journalReferenceKey is the key holding the ID of the gateway transaction of a journal
+	journalReferenceKey = "reference"
+
+	// journalKindKey is the key holding the kind of a journal
+	journalKindKey = "kind"
+
+	// invoicePaidKey is the key holding the amount paid against an invoice in paise
+	invoicePaidKey = "paid"
+
+	// userPayoutVersionKey is the key holding a counter which is bumped whenever a vendor is paid out
+	userPayoutVersionKey = "payout_version"
+)
+
+var ledgerCollection = db.Collection(ledgerCollectionKey)
+
+// ErrInsufficientBalance is returned when a vendor is paid out more than it is owed
+var ErrInsufficientBalance = errors.New("Amount exceeds the balance owed to the vendor")
+
+// ErrUnpaidInvoices is returned when a vendor is paid out more than it is owed for the invoices which have been paid by the clients
+var ErrUnpaidInvoices = errors.New("Amount exceeds the balance owed to the vendor for the invoices paid by the clients so far")
+
+// insertJournal validates and inserts a journal into the ledger
+func insertJournal(ctx context.Context, journal *types.Journal) error {
+	if err := journal.Validate(); err != nil {
+		return err
+	}
+	res, err := ledgerCollection.InsertOne(ctx, journal)
+	if err != nil {
+		return err
+	}
+	journal.ID, _ = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// recordInvoice inserts the journal of a newly issued invoice into the ledger
+// Line items whose vendor key can't be decrypted are credited to the unallocated account instead of failing the invoice
+func recordInvoice(ctx context.Context, invoice *types.Invoice) error {
+	vendorEmails := make(map[string]string)
+	for _, item := range invoice.LineItems {
+		vendorEmail, err := utils.Decrypt(item.VendorKey)
+		if err != nil {
+			// The line item is credited to the unallocated account so that the invoice can still be issued
+			utils.LogError("Mongo-Ledger-1", fmt.Errorf("Could not decrypt vendor key %s of invoice %s: %v", item.VendorKey, invoice.Number, err))
+			continue
+		}
+		vendorEmails[item.VendorKey] = vendorEmail
+	}
+	return insertJournal(ctx, invoice.Journal(vendorEmails))
+}
+
+// fetchAccountBalances returns the totals of all accounts matching the filter on the postings
+func fetchAccountBalances(ctx context.Context, filter types.M) ([]types.AccountBalance, error) {
+	pipeline := []types.M{
+		{"$unwind": "$" + journalPostingsKey},
+		{"$match": filter},
+		{"$group": types.M{
+			primaryKey: "$" + concat(journalPostingsKey, postingAccountKey),
+			"debit":    types.M{"$sum": "$" + concat(journalPostingsKey, "debit")},
+			"credit":   types.M{"$sum": "$" + concat(journalPostingsKey, "credit")},
+		}},
+		{"$sort": types.M{primaryKey: 1}},
+	}
+	cursor, err := ledgerCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]types.AccountBalance, 0)
+	err = cursor.All(ctx, &balances)
+	return balances, err
+}
+
+// fetchAccountBalance returns the totals of a single account
+func fetchAccountBalance(ctx context.Context, account string) (types.AccountBalance, error) {
+	balances, err := fetchAccountBalances(ctx, types.M{
+		concat(journalPostingsKey, postingAccountKey): account,
+	})
+	if err != nil || len(balances) == 0 {
+		return types.AccountBalance{Account: account}, err
+	}
+	return balances[0], nil
+}
+
+// fetchUnpaidCredits returns the amount credited to a vendor by the invoices which haven't been fully paid by their clients yet
+func fetchUnpaidCredits(ctx context.Context, vendorEmail string) (int64, error) {
+	account := types.VendorAccount(vendorEmail)
+	cursor, err := ledgerCollection.Find(ctx, types.M{
+		journalKindKey: types.INVOICE,
+		concat(journalPostingsKey, postingAccountKey): account,
+	})
+	if err != nil {
+		return 0, err
+	}
+	journals := make([]types.Journal, 0)
+	if err := cursor.All(ctx, &journals); err != nil {
+		return 0, err
+	}
+	credits := make(map[string]int64)
+	numbers := make([]string, 0, len(journals))
+	for _, journal := range journals {
+		numbers = append(numbers, journal.Invoice)
+		for _, posting := range journal.Postings {
+			if posting.Account == account {
+				credits[journal.Invoice] += posting.Credit - posting.Debit
+			}
+		}
+	}
+	if len(numbers) == 0 {
+		return 0, nil
+	}
+
+	cursor, err = invoiceCollection.Find(ctx, types.M{
+		invoiceNumberKey: types.M{"$in": numbers},
+	})
+	if err != nil {
+		return 0, err
+	}
+	invoices := make([]types.Invoice, 0)
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return 0, err
+	}
+	unpaid := int64(0)
+	for idx := range invoices {
+		if invoices[idx].Outstanding() > 0 {
+			unpaid += credits[invoices[idx].Number]
+		}
+	}
+	return unpaid, nil
+}
+
+// fetchPayableBalance returns the amount owed to a vendor for the invoices which have been fully paid by their clients
+func fetchPayableBalance(ctx context.Context, vendorEmail string) (int64, error) {
+	balance, err := fetchAccountBalance(ctx, types.VendorAccount(vendorEmail))
+	if err != nil {
+		return 0, err
+	}
+	unpaid, err := fetchUnpaidCredits(ctx, vendorEmail)
+	if err != nil {
+		return 0, err
+	}
+	return -balance.Balance() - unpaid, nil
+}
+
+// FetchPayableBalance returns the amount owed to a vendor which can be paid out
+// i.e the vendor's balance excluding its share of the invoices which haven't been fully paid by their clients
+func FetchPayableBalance(vendorEmail string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return fetchPayableBalance(ctx, vendorEmail)
+}
+
+// FetchAccountBalance returns the totals of a single account in the ledger
+func FetchAccountBalance(account string) (types.AccountBalance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return fetchAccountBalance(ctx, account)
+}
+
+// FetchAccountBalances returns the totals of all accounts in the ledger
+func FetchAccountBalances() ([]types.AccountBalance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return fetchAccountBalances(ctx, types.M{})
+}
+
+// FetchJournalsByAccount returns all journals having a posting to the account, latest first
+func FetchJournalsByAccount(account string) ([]types.M, error) {
+	return fetchDocs(ledgerCollection, types.M{
+		concat(journalPostingsKey, postingAccountKey): account,
+	}, options.Find().SetSort(types.M{
+		createdKey: -1,
+	}))
+}
+
+// RecordPayment records a payment made by a client against an invoice
+// The amount paid against the invoice is incremented only if the invoice is not overpaid in the process,
+// else ErrConflict is returned as the invoice must have been paid by another request in the meantime
+func RecordPayment(invoice *types.Invoice, amount int64, reference string) error {
+	journal := &types.Journal{
+		Kind:      types.PAYMENT,
+		Invoice:   invoice.Number,
+		Reference: reference,
+		Created:   time.Now().Unix(),
+	}
+	journal.Debit(types.CashAccount, amount).Credit(types.ClientAccount(invoice.Client), amount)
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		res, err := invoiceCollection.UpdateOne(ctx, types.M{
+			primaryKey:     invoice.ID,
+			invoicePaidKey: types.M{"$lte": invoice.Due() - amount},
+		}, types.M{
+			"$inc": types.M{
+				invoicePaidKey: amount,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrConflict
+		}
+		return insertJournal(ctx, journal)
+	})
+}
+
+// RecordPayout records a payout to a vendor and returns its journal
+// A vendor can only be paid its share of the invoices which have been fully paid by their clients
+// The vendor's document is written to within the transaction so that concurrent payouts
+// conflict and the vendor can never be paid out more than it is owed
+func RecordPayout(vendorEmail string, amount int64) (*types.Journal, error) {
+	journal := &types.Journal{
+		Kind:    types.PAYOUT,
+		Created: time.Now().Unix(),
+	}
+	journal.Debit(types.VendorAccount(vendorEmail), amount).Credit(types.CashAccount, amount)
+
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		if _, err := userCollection.UpdateOne(ctx, types.M{
+			userEmailKey: vendorEmail,
+		}, types.M{
+			"$inc": types.M{
+				userPayoutVersionKey: 1,
+			},
+		}); err != nil {
+			return err
+		}
+		balance, err := fetchAccountBalance(ctx, types.VendorAccount(vendorEmail))
+		if err != nil {
+			return err
+		}
+		if -balance.Balance() < amount {
+			return ErrInsufficientBalance
+		}
+		// Vendors are only paid out of the money received from the clients
+		unpaid, err := fetchUnpaidCredits(ctx, vendorEmail)
+		if err != nil {
+			return err
+		}
+		if -balance.Balance()-unpaid < amount {
+			return ErrUnpaidInvoices
+		}
+		journal.ID = primitive.ObjectID{}
+		return insertJournal(ctx, journal)
+	})
+	return journal, err
+}
+
+// SetJournalReference sets the ID of the gateway transaction on a journal
+func SetJournalReference(journalID primitive.ObjectID, reference string) error {
+	return updateOne(ledgerCollection, types.M{
+		primaryKey: journalID,
+	}, types.M{
+		journalReferenceKey: reference,
+	})
+}
+
+// ReversePayout records the reversal of a payout which was rejected by the payment gateway
+func ReversePayout(payout *types.Journal) error {
+	journal := &types.Journal{
+		Kind:      types.REVERSAL,
+		Reference: payout.ID.Hex(),
+		Created:   time.Now().Unix(),
+	}
+	for _, posting := range payout.Postings {
+		journal.Postings = append(journal.Postings, types.Posting{
+			Account: posting.Account,
+			Debit:   posting.Credit,
+			Credit:  posting.Debit,
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return insertJournal(ctx, journal)
+}
@@ -0,0 +1,164 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// messageCollectionKey is the collection for the negotiation messages between clients and vendors
+	messageCollectionKey = "messages"
+
+	// messagePostIDKey is the key holding the ID of the post a message is concerned with
+	messagePostIDKey = "post_id"
+
+	// messageOfferKey is the key holding the key of the vendor's offer a message is concerned with
+	messageOfferKey = "offer_key"
+
+	// messageVendorKey is the key holding the email ID of the vendor of a thread
+	messageVendorKey = "vendor"
+
+	// messageSenderKey is the key holding the email ID of the sender of a message
+	messageSenderKey = "sender"
+
+	// messageSenderRoleKey is the key holding the role of the sender of a message
+	messageSenderRoleKey = "sender_role"
+
+	// messageReadKey is the key holding the timestamp at which a message was read by its recipient
+	messageReadKey = "read"
+)
+
+var messageCollection = db.Collection(messageCollectionKey)
+
+// InsertMessage appends a message to its thread
+func InsertMessage(message *types.Message) (interface{}, error) {
+	return insertOne(messageCollection, message)
+}
+
+// FetchThread returns all messages exchanged over a vendor's offer on a post, oldest first
+func FetchThread(postID, offerKey string) ([]types.Message, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	cursor, err := messageCollection.Find(ctx, types.M{
+		messagePostIDKey: docID,
+		messageOfferKey:  offerKey,
+	}, options.Find().SetSort(types.M{
+		createdKey: 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]types.Message, 0)
+	err = cursor.All(ctx, &messages)
+	return messages, err
+}
+
+// MarkThreadRead marks all unread messages of a thread sent to the reader as read
+// The param "readerRole" is the role of the reader, i.e messages sent by the other party are marked
+func MarkThreadRead(postID, offerKey, readerRole string) error {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	_, err = messageCollection.UpdateMany(ctx, types.M{
+		messagePostIDKey: docID,
+		messageOfferKey:  offerKey,
+		messageSenderRoleKey: types.M{
+			"$ne": readerRole,
+		},
+		messageReadKey: types.M{
+			"$exists": false,
+		},
+	}, types.M{
+		"$set": types.M{
+			messageReadKey: time.Now().Unix(),
+		},
+	})
+	return err
+}
+
+// FetchThreadsByPost returns every thread of a post along with its latest message and the number of messages unread by the reader
+// The email IDs of the vendors and the senders are excluded
+func FetchThreadsByPost(postID, readerRole string) ([]types.M, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(messageCollection, []types.M{
+		{
+			"$match": types.M{
+				messagePostIDKey: docID,
+			},
+		},
+		{
+			"$sort": types.M{
+				createdKey: -1,
+			},
+		},
+		{
+			"$group": types.M{
+				primaryKey: "$" + messageOfferKey,
+				"latest": types.M{
+					"$first": "$$ROOT",
+				},
+				"unread": types.M{
+					"$sum": types.M{
+						"$cond": []interface{}{
+							types.M{
+								"$and": []interface{}{
+									types.M{"$ne": []interface{}{"$" + messageSenderRoleKey, readerRole}},
+									types.M{"$eq": []interface{}{types.M{"$ifNull": []interface{}{"$" + messageReadKey, 0}}, 0}},
+								},
+							},
+							1,
+							0,
+						},
+					},
+				},
+			},
+		},
+		{
+			"$project": types.M{
+				concat("latest", messageVendorKey): 0,
+				concat("latest", messageSenderKey): 0,
+			},
+		},
+		{
+			"$sort": types.M{
+				concat("latest", createdKey): -1,
+			},
+		},
+	})
+}
+
+// HasOffer checks if a vendor holds either a pending or an accepted offer on a post
+// The param "offerKey" is the vendor's email address encrypted with AES-256
+func HasOffer(postID, offerKey string) (bool, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return false, err
+	}
+	count, err := countDocs(postCollection, types.M{
+		primaryKey: docID,
+		"$or": []types.M{
+			{concat(postOffersKey, offerKey): types.M{"$exists": true}},
+			{concat(postAcceptedOffersKey, offerKey): types.M{"$exists": true}},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
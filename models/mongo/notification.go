@@ -1,12 +1,15 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/reverie/types"
 	"github.com/reverie/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -20,6 +23,15 @@ const (
 	// notificationReadKey denotes if a notification is read or not
 	notificationReadKey = "read"
 
+	// notificationTypeKey is the key holding the type of a notification
+	notificationTypeKey = "type"
+
+	// notificationResolutionKey is the key holding the vendor's response to a change request
+	notificationResolutionKey = "resolution"
+
+	// notificationReasonKey is the key holding the vendor's reason for declining a change request
+	notificationReasonKey = "reason"
+
 	// notificationPageSize is the maximum number of notifications per batch
 	notificationPageSize = 30
 )
@@ -81,18 +93,41 @@ func NotifyVendorOnAcceptance(postID, vendorEmail string) {
 	notifyVendor(postID, vendorEmail, "Your offer on post %s has been accepted")
 }
 
+// NotifyVendorOnPartialAcceptance notifies a vendor when only a part of its offer on a post has been accepted
+// spelling out the accepted part and whether the remainder stays pending or has been released
+func NotifyVendorOnPartialAcceptance(postID, vendorEmail string, accepted, remainder types.Inventory, kept bool) {
+	fate := "has been released"
+	if kept {
+		fate = "stays pending"
+	}
+	notifyVendor(postID, vendorEmail, fmt.Sprintf("Your offer on post %%s has been partially accepted. Accepted: %s. The rest (%s) %s",
+		strings.ReplaceAll(accepted.Describe(), "%", "%%"), strings.ReplaceAll(remainder.Describe(), "%", "%%"), fate))
+}
+
 // NotifyVendorOnRejection notifies a vendor when his offer on a post has been rejected
 func NotifyVendorOnRejection(postID, vendorEmail string) {
 	notifyVendor(postID, vendorEmail, "Your offer on post %s has been rejected")
 }
 
+// NotifyVendorOnRestoration notifies a vendor when its removed offer on a post has been restored by us
+func NotifyVendorOnRestoration(postID, vendorEmail string) {
+	notifyVendor(postID, vendorEmail, "Your offer on post %s has been restored as a pending offer")
+}
+
+// NotifyVendorOnMessage notifies a vendor when the client sends a message over the vendor's offer on a post
+func NotifyVendorOnMessage(postID, vendorEmail string) {
+	notifyVendor(postID, vendorEmail, "You have a new message from the client of post %s")
+}
+
 // BulkNotifyVendors notfies all vendors whose offer has been accepted whenever there is a change in the post's status
 func BulkNotifyVendors(postID, status string) {
 	messageTemplate := ""
 
 	switch status {
 	case types.ONGOING:
-		messageTemplate = "Work on post %s has started. Kindly deliver your equipments soon."
+		messageTemplate = "Work on post %s has started. Kindly deliver your equipments soon and keep the shipment updated."
+	case types.COMPLETION_REQUESTED:
+		messageTemplate = "Client has marked post %s as complete. Kindly confirm or dispute the completion."
 	case types.COMPLETED:
 		messageTemplate = "Post %s has completed successfully"
 	case types.DELETED:
@@ -100,7 +135,12 @@ func BulkNotifyVendors(postID, status string) {
 	case types.OPEN:
 		messageTemplate = "Work on post %s is temporarily halted"
 	}
+	NotifyAcceptedVendors(postID, messageTemplate)
+}
 
+// NotifyAcceptedVendors notifies all vendors whose offers have been accepted on a post
+// The message template is formatted with the post's name
+func NotifyAcceptedVendors(postID, messageTemplate string) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
 		utils.LogError("Notification-Controller-4", err)
@@ -169,6 +209,60 @@ func NotifyClient(postID, messageTemplate string) {
 	}
 }
 
+// NotifyShipmentUpdate notifies the client or the vendor of a shipment whenever there is a change in the shipment
+// The message template is formatted with the post's name
+func NotifyShipmentUpdate(shipment *types.Shipment, recipent, messageTemplate string) {
+	postName, err := FetchPostName(shipment.PostID.Hex())
+	if err != nil {
+		utils.LogError("Notification-Controller-11", err)
+		return
+	}
+	_, err = insertOne(notificationCollection, types.Notification{
+		PostID:   shipment.PostID,
+		Recipent: recipent,
+		Type:     types.INFO,
+		Message:  fmt.Sprintf(messageTemplate, postName),
+		Read:     false,
+		Created:  time.Now().Unix(),
+	})
+	if err != nil {
+		utils.LogError("Notification-Controller-12", err)
+	}
+}
+
+// NotifyRatee notifies a user whenever it is rated for a post
+func NotifyRatee(rating *types.Rating, postName string) {
+	_, err := insertOne(notificationCollection, types.Notification{
+		PostID:   rating.PostID,
+		Recipent: rating.Ratee,
+		Type:     types.INFO,
+		Message:  fmt.Sprintf("%s has rated you for post %s", rating.RaterName, postName),
+		Read:     false,
+		Created:  time.Now().Unix(),
+	})
+	if err != nil {
+		utils.LogError("Notification-Controller-13", err)
+	}
+}
+
+// NotifyInventoryRequestReview notifies a vendor whenever its inventory change request is approved or rejected
+func NotifyInventoryRequestReview(request *types.InventoryRequest) {
+	message := fmt.Sprintf("Your inventory change request has been %s", strings.ToLower(request.Status))
+	if request.ReviewReason != types.EMPTY {
+		message = fmt.Sprintf("%s: %s", message, request.ReviewReason)
+	}
+	_, err := insertOne(notificationCollection, types.Notification{
+		Recipent: request.Vendor,
+		Type:     types.INFO,
+		Message:  message,
+		Read:     false,
+		Created:  time.Now().Unix(),
+	})
+	if err != nil {
+		utils.LogError("Notification-Controller-14", err)
+	}
+}
+
 // NotifyOfferChangeToVendor notifies a vendor whenever a client requests changes on his offer
 func NotifyOfferChangeToVendor(postID, vendorEmail string, offerChange *types.Inventory) error {
 	docID, err := primitive.ObjectIDFromHex(postID)
@@ -190,3 +284,75 @@ func NotifyOfferChangeToVendor(postID, vendorEmail string, offerChange *types.In
 	})
 	return err
 }
+
+// FetchOfferChangeRequest returns a change request sent to a vendor given the ID of its notification
+func FetchOfferChangeRequest(notificationID, vendorEmail string) (*types.Notification, error) {
+	docID, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	request := &types.Notification{}
+	err = notificationCollection.FindOne(ctx, types.M{
+		primaryKey:              docID,
+		notificationRecipentKey: vendorEmail,
+		notificationTypeKey:     types.RequestOfferChange,
+	}).Decode(request)
+	return request, err
+}
+
+// resolveOfferChangeRequest marks a change request as ACCEPTED or DECLINED along with marking it read
+// ErrConflict is returned if the request has already been resolved
+func resolveOfferChangeRequest(ctx context.Context, request *types.Notification, resolution, reason string) error {
+	update := types.M{
+		notificationResolutionKey: resolution,
+		notificationReadKey:       true,
+	}
+	if reason != types.EMPTY {
+		update[notificationReasonKey] = reason
+	}
+	return conflictOnNoDocuments(notificationCollection.FindOneAndUpdate(ctx, types.M{
+		primaryKey: request.ID,
+		notificationResolutionKey: types.M{
+			"$exists": false,
+		},
+	}, types.M{
+		"$set": update,
+	}).Err())
+}
+
+// AcceptOfferChange rewrites the vendor's pending offer with the one desired by the client and resolves the change request
+// within a single transaction and returns the vendor's previous offer
+// ErrConflict is returned if the request has already been resolved, the post is no longer OPEN
+// or the vendor's offer has been accepted, retracted or rejected in the meantime
+func AcceptOfferChange(request *types.Notification, offerKey string, offer types.Offer) (*types.Offer, error) {
+	var previous *types.Offer
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		if err := resolveOfferChangeRequest(ctx, request, types.ACCEPTED, types.EMPTY); err != nil {
+			return err
+		}
+		var err error
+		previous, err = updateOffer(ctx, types.M{
+			primaryKey:    request.PostID,
+			postStatusKey: types.OPEN,
+			concat(postOffersKey, offerKey): types.M{
+				"$exists": true,
+			},
+		}, types.M{
+			"$set": types.M{
+				concat(postOffersKey, offerKey): offer,
+			},
+		}, postOffersKey, offerKey)
+		return conflictOnNoDocuments(err)
+	})
+	return previous, err
+}
+
+// DeclineOfferChange resolves a change request leaving the vendor's offer untouched
+func DeclineOfferChange(request *types.Notification, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return resolveOfferChangeRequest(ctx, request, types.DECLINED, reason)
+}
@@ -2,13 +2,14 @@ package mongo
 
 import (
 	"context"
-	"reflect"
 	"strings"
 	"time"
 
 	"github.com/reverie/types"
 	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -25,6 +26,9 @@ const (
 	// postRequirementsKey is the key denoting the requirements for a post
 	postRequirementsKey = "requirements"
 
+	// postRequirementSpecsKey is the key denoting the specifications which the required items need to meet
+	postRequirementSpecsKey = "requirement_specs"
+
 	// postOffersKey is the key denoting the offers made to a post by a vendor
 	postOffersKey = "offers"
 
@@ -46,14 +50,44 @@ const (
 	// postStatusKey is the key holding the status of a post
 	postStatusKey = "status"
 
+	// postHistoryKey is the key holding the status transitions of a post
+	postHistoryKey = "history"
+
+	// postCompletionResponsesKey is the key holding the vendors' responses to the completion requested by the client
+	postCompletionResponsesKey = "completion_responses"
+
+	// postWorkPeriodsKey is the key holding the spans of time in which a post was ONGOING
+	postWorkPeriodsKey = "work_periods"
+
+	// workPeriodStopKey is the key holding the end of a work period, 0 for the running work period
+	workPeriodStopKey = "stop"
+
+	// postStartDateKey is the key holding the timestamp at which the job is scheduled to start
+	postStartDateKey = "start_date"
+
+	// postEndDateKey is the key holding the timestamp at which the job is scheduled to end
+	postEndDateKey = "end_date"
+
 	// createdKey is the key denoting the timestamp of creation of a job request
 	createdKey = "created"
 
 	// updatedKey is the key denoting the timestamp at which the job request was last updated
 	updatedKey = "updated"
 
+	// postDistanceKey is the key holding the distance (in kilometres) of a post from the vendor's location in geo searches
+	postDistanceKey = "distance"
+
+	// postOwnerProfileKey is the key temporarily holding the profile of a post's owner in aggregations
+	postOwnerProfileKey = "owner_profile"
+
+	// postClientRatingKey is the key holding the overall rating of a post's owner in searches
+	postClientRatingKey = "client_rating"
+
 	// postPageSize is the maximum number of posts retrieved in one batch for the vendor
 	postPageSize = 30
+
+	// metresPerKm is used for converting distances between kilometres and metres
+	metresPerKm = 1000
 )
 
 // Constants for offer schema
@@ -64,11 +98,23 @@ const (
 	// contents of the offer in the form of types.Inventory
 	offerContentKey = "content"
 
+	// timestamp at which the offer was first accepted
+	offerAcceptedKey = "accepted"
+
+	// specifications of the offered items in the form of types.EquipmentSpecs
+	offerSpecsKey = "specs"
+
 	// time of creation of the offer
 	offerTimestampKey = "created"
 
-	// The fees charged for the vendor's services in indian rupees per day
+	// legacy fee for the entire offer in indian rupees per day
 	offerRateKey = "rate"
+
+	// prices of the offered items in the form of types.Prices
+	offerPricesKey = "prices"
+
+	// currency in which the offer is priced
+	offerCurrencyKey = "currency"
 )
 
 var postCollection = db.Collection(postCollectionKey)
@@ -78,6 +124,21 @@ func concat(keys ...string) string {
 	return strings.Join(keys, ".")
 }
 
+// inventoryIncrements makes a map for incrementing/decrementing inventory items nested under a key
+// The items are multiplied by the multiplier, hence -1 can be used for decrementing
+// Items with zero values are skipped
+func inventoryIncrements(inventory types.Inventory, multiplier int64, prefix ...string) map[string]int64 {
+	incrementMap := make(map[string]int64)
+	for item, value := range inventory {
+		if value == 0 {
+			continue
+		}
+		key := concat(append(prefix[:len(prefix):len(prefix)], item)...)
+		incrementMap[key] = value * multiplier
+	}
+	return incrementMap
+}
+
 // CreatePost is an abstraction over InsertOne which inserts a post
 func CreatePost(post *types.Post) (interface{}, error) {
 	return insertOne(postCollection, post)
@@ -99,122 +160,180 @@ func IsPostOwner(postID, clientEmail string) (bool, error) {
 	return count == 1, nil
 }
 
-// UpdatePost updates a post by a client
-func UpdatePost(postID string, post *types.PostUpdate) error {
+// UpdatePost updates a post by a client and returns the values of the updated fields prior to the update
+func UpdatePost(postID string, post *types.PostUpdate) (*types.PostUpdate, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	filter := types.M{
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	previous := &types.PostUpdate{}
+	err = postCollection.FindOneAndUpdate(ctx, types.M{
 		primaryKey: docID,
+	}, types.M{
+		"$set": post,
+	}, options.FindOneAndUpdate().SetProjection(types.M{
+		postDescriptionKey:      1,
+		postLocationKey:         1,
+		postRequirementsKey:     1,
+		postRequirementSpecsKey: 1,
+		postStartDateKey:        1,
+		postEndDateKey:          1,
+	})).Decode(previous)
+	return previous, err
+}
+
+// updateOffer applies an update to the offer held by "offerKey" in the given offers field of a post
+// Returns the offer prior to the update, nil if the post held no such offer
+func updateOffer(ctx context.Context, filter types.M, update types.M, offersKey, offerKey string) (*types.Offer, error) {
+	post := &types.Post{}
+	if err := postCollection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetProjection(types.M{
+		concat(offersKey, offerKey): 1,
+	})).Decode(post); err != nil {
+		return nil, err
+	}
+	offers := post.Offers
+	if offersKey == postAcceptedOffersKey {
+		offers = post.AcceptedOffers
 	}
-	return updateOne(postCollection, filter, post)
+	if offer, ok := offers[offerKey]; ok {
+		return &offer, nil
+	}
+	return nil, nil
 }
 
 // UpdatePostOffers adds/updates an offer to an OPEN post
-func UpdatePostOffers(postID, vendorEmail string, offer types.Offer) error {
+// Returns the vendor's previous offer, nil if it is a new offer
+func UpdatePostOffers(postID, vendorEmail string, offer types.Offer) (*types.Offer, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return err
-	}
-	filter := types.M{
-		primaryKey: docID,
+		return nil, err
 	}
 	vendorEmailKey, err := utils.Encrypt(vendorEmail)
 	if err != nil {
-		return err
-	}
-	updatePayload := types.M{
-		concat(postOffersKey, vendorEmailKey): offer,
+		return nil, err
 	}
-	return updateOne(postCollection, filter, updatePayload)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return updateOffer(ctx, types.M{
+		primaryKey: docID,
+	}, types.M{
+		"$set": types.M{
+			concat(postOffersKey, vendorEmailKey): offer,
+		},
+	}, postOffersKey, vendorEmailKey)
 }
 
 // RetractPostOffer removes an offer from an OPEN post by a vendor
-func RetractPostOffer(postID, vendorEmail string) error {
+// Returns the retracted offer, nil if the vendor had no offer on the post
+func RetractPostOffer(postID, vendorEmail string) (*types.Offer, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return err
-	}
-	filter := types.M{
-		primaryKey: docID,
+		return nil, err
 	}
 	vendorEmailKey, err := utils.Encrypt(vendorEmail)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	return postCollection.FindOneAndUpdate(ctx, filter, types.M{
+	return updateOffer(ctx, types.M{
+		primaryKey: docID,
+	}, types.M{
 		"$unset": types.M{
 			concat(postOffersKey, vendorEmailKey): "",
 		},
-	}).Err()
+	}, postOffersKey, vendorEmailKey)
 }
 
 // RejectAcceptedOffer removes an accepted offer from an OPEN post by a client
+// and releases the vendor's reservation for that post and withdraws its shipment within a single transaction
 // The param "offerKey" is key holding the offer in the post
 // It is the vendor's email address encrypted with AES-256
-func RejectAcceptedOffer(postID, offerKey string, offer types.Inventory) error {
+func RejectAcceptedOffer(postID, offerKey, vendorEmail, actor string, offer types.Inventory) error {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
 		return err
 	}
 	filter := types.M{
-		primaryKey: docID,
-	}
-
-	// Make a map for incrementing the posts's current requirements
-	incrementMap := make(map[string]int64)
-
-	offerValues := reflect.ValueOf(offer)
-	offerKeys := reflect.TypeOf(offer)
-
-	for i := 0; i < offerValues.NumField(); i++ {
-		value := offerValues.Field(i).Int()
-		if value == 0 {
-			continue
-		}
-		key := concat(postRequirementsKey, offerKeys.Field(i).Name)
-		incrementMap[key] = value
+		primaryKey:    docID,
+		postStatusKey: types.OPEN,
+		concat(postAcceptedOffersKey, offerKey): types.M{
+			"$exists": true,
+		},
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
-	defer cancel()
-	return postCollection.FindOneAndUpdate(ctx, filter, types.M{
+	update := types.M{
 		"$unset": types.M{
 			concat(postAcceptedOffersKey, offerKey): "",
 		},
-		"$inc": incrementMap,
-	}).Err()
+		"$inc": inventoryIncrements(offer, 1, postRequirementsKey),
+	}
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		if err := postCollection.FindOneAndUpdate(ctx, filter, update).Err(); err != nil {
+			return conflictOnNoDocuments(err)
+		}
+		if err := releaseReservation(ctx, vendorEmail, docID); err != nil {
+			return err
+		}
+		// The post might have been halted after its shipments were scheduled
+		return cancelShipment(ctx, docID, offerKey, actor)
+	})
 }
 
 // RejectPendingOffer removes a pending offer from an OPEN post by a client
+// Returns the rejected offer, nil if the post held no such offer
 // The param "offerKey" is key holding the offer in the post
 // It is the vendor's email address encrypted with AES-256
-func RejectPendingOffer(postID, offerKey string) error {
+func RejectPendingOffer(postID, offerKey string) (*types.Offer, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return err
-	}
-	filter := types.M{
-		primaryKey: docID,
+		return nil, err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
-	return postCollection.FindOneAndUpdate(ctx, filter, types.M{
+	return updateOffer(ctx, types.M{
+		primaryKey: docID,
+	}, types.M{
 		"$unset": types.M{
 			concat(postOffersKey, offerKey): "",
 		},
-	}).Err()
+	}, postOffersKey, offerKey)
+}
+
+// RestoreOffer puts back an offer removed from an OPEN post as a pending offer
+// ErrConflict is returned if the post is no longer OPEN or the vendor has made or been accepted for another offer since
+func RestoreOffer(postID, offerKey string, offer *types.Offer) error {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return conflictOnNoDocuments(postCollection.FindOneAndUpdate(ctx, types.M{
+		primaryKey:    docID,
+		postStatusKey: types.OPEN,
+		concat(postOffersKey, offerKey): types.M{
+			"$exists": false,
+		},
+		concat(postAcceptedOffersKey, offerKey): types.M{
+			"$exists": false,
+		},
+	}, types.M{
+		"$set": types.M{
+			concat(postOffersKey, offerKey): offer,
+		},
+	}).Err())
 }
 
-// FetchActivePostsByClient returns all open/ongoing posts created by a client
+// FetchActivePostsByClient returns all open/ongoing posts created by a client including the ones awaiting completion
 func FetchActivePostsByClient(clientEmail string) ([]types.M, error) {
 	return fetchDocs(postCollection, types.M{
 		postOwnerKey: clientEmail,
 		postStatusKey: types.M{
-			"$in": []string{types.OPEN, types.ONGOING},
+			"$in": []string{types.OPEN, types.ONGOING, types.COMPLETION_REQUESTED},
 		},
 	}, options.Find().SetSort(types.M{
 		updatedKey: -1,
@@ -239,22 +358,146 @@ func FetchSinglePostByClient(postID string) (*types.Post, error) {
 	return post, err
 }
 
-// UpdatePostStatus updates the status of the post
-func UpdatePostStatus(postID, newStatus string) error {
+// statusTransitionUpdate returns the update for moving a post to a new status at the given timestamp
+// along with recording the transition in its history
+// A work period is started when the post becomes ONGOING and the running work period is stopped when it leaves ONGOING
+// Posts which were ONGOING before work periods were introduced don't hold any, hence the param "hasWorkPeriods"
+// as MongoDB rejects array filters on a missing array
+func statusTransitionUpdate(actor, currentStatus, newStatus string, timestamp int64, hasWorkPeriods bool) (types.M, *options.FindOneAndUpdateOptions) {
+	opts := options.FindOneAndUpdate()
+	updatePayload := types.M{
+		postStatusKey: newStatus,
+	}
+	pushPayload := types.M{
+		postHistoryKey: types.StatusTransition{
+			Actor:     actor,
+			From:      currentStatus,
+			To:        newStatus,
+			Timestamp: timestamp,
+		},
+	}
+	if newStatus == types.ONGOING {
+		updatePayload[updatedKey] = timestamp
+		pushPayload[postWorkPeriodsKey] = types.WorkPeriod{
+			Start: timestamp,
+		}
+	}
+	update := types.M{
+		"$set":  updatePayload,
+		"$push": pushPayload,
+	}
+	// Every completion request is responded to afresh by the vendors
+	if newStatus == types.COMPLETION_REQUESTED {
+		update["$unset"] = types.M{
+			postCompletionResponsesKey: "",
+		}
+	}
+	if currentStatus == types.ONGOING && hasWorkPeriods {
+		updatePayload[concat(postWorkPeriodsKey, "$[running]", workPeriodStopKey)] = timestamp
+		opts.SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{
+				types.M{concat("running", workPeriodStopKey): 0},
+			},
+		})
+	}
+	return update, opts
+}
+
+// TransitionPostStatus moves a post from its current status to a new one and records the transition in its history
+// The current status is a part of the filter so that the update is atomic i.e if the status
+// was changed by someone else in the meantime then ErrConflict is returned
+// Within the same transaction, shipments are scheduled for the accepted offers when the post becomes ONGOING
+// and the reservations of all vendors bound to the post are released when it is DELETED
+func TransitionPostStatus(postID, actor, currentStatus, newStatus string) error {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
 		return err
 	}
 	filter := types.M{
-		primaryKey: docID,
+		primaryKey:    docID,
+		postStatusKey: currentStatus,
 	}
-	updatePayload := types.M{
-		postStatusKey: newStatus,
+	timestamp := time.Now().Unix()
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		hasWorkPeriods := false
+		if currentStatus == types.ONGOING {
+			count, err := postCollection.CountDocuments(ctx, types.M{
+				primaryKey:         docID,
+				postWorkPeriodsKey: types.M{"$exists": true},
+			})
+			if err != nil {
+				return err
+			}
+			hasWorkPeriods = count > 0
+		}
+		update, opts := statusTransitionUpdate(actor, currentStatus, newStatus, timestamp, hasWorkPeriods)
+		opts.SetProjection(types.M{
+			postOwnerKey:          1,
+			postAcceptedOffersKey: 1,
+		})
+		post := &types.Post{}
+		if err := postCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(post); err != nil {
+			return conflictOnNoDocuments(err)
+		}
+		switch newStatus {
+		case types.ONGOING:
+			return scheduleShipments(ctx, post, actor)
+		case types.DELETED:
+			return releasePostReservations(ctx, docID)
+		}
+		return nil
+	})
+}
+
+// CompletePost marks a COMPLETION_REQUESTED post as COMPLETED, releases the reservations bound to it and persists its invoice
+// along with recording it in the payments ledger in a single transaction
+// The invoice is computed from the given post, hence the post's history must not have changed in the meantime else ErrConflict is returned
+func CompletePost(post *types.Post, actor string, invoice *types.Invoice) error {
+	filter := types.M{
+		primaryKey:     post.ID,
+		postStatusKey:  types.COMPLETION_REQUESTED,
+		postHistoryKey: types.M{"$size": len(post.History)},
 	}
-	if newStatus == types.ONGOING {
-		updatePayload[updatedKey] = time.Now().Unix()
+	if len(post.History) == 0 {
+		filter[postHistoryKey] = types.M{"$exists": false}
 	}
-	return updateOne(postCollection, filter, updatePayload)
+	update, opts := statusTransitionUpdate(actor, types.COMPLETION_REQUESTED, types.COMPLETED, invoice.Issued, len(post.WorkPeriods) > 0)
+
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		if err := postCollection.FindOneAndUpdate(ctx, filter, update, opts).Err(); err != nil {
+			return conflictOnNoDocuments(err)
+		}
+		if err := releasePostReservations(ctx, post.ID); err != nil {
+			return err
+		}
+		if err := insertInvoice(ctx, invoice); err != nil {
+			return err
+		}
+		return recordInvoice(ctx, invoice)
+	})
+}
+
+// RespondToCompletion records a vendor's confirmation or dispute of the completion requested by the client
+// A vendor can change its response till the post is COMPLETED, ErrConflict is returned if the post is not awaiting completion
+func RespondToCompletion(postID, vendorEmail string, response types.CompletionResponse) error {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return err
+	}
+	vendorEmailKey, err := utils.Encrypt(vendorEmail)
+	if err != nil {
+		return err
+	}
+	return conflictOnNoDocuments(updateOne(postCollection, types.M{
+		primaryKey:    docID,
+		postStatusKey: types.COMPLETION_REQUESTED,
+		concat(postAcceptedOffersKey, vendorEmailKey): types.M{
+			"$exists": true,
+		},
+	}, types.M{
+		concat(postCompletionResponsesKey, vendorEmailKey): response,
+	}))
 }
 
 // FetchSinglePostByVendor returns a single post given its id
@@ -279,8 +522,12 @@ func FetchSinglePostByVendor(postID, vendorEmail string) (*types.Post, error) {
 		postOwnerNameKey:                      1,
 		postLocationKey:                       1,
 		postRequirementsKey:                   1,
+		postRequirementSpecsKey:               1,
 		postCommentsKey:                       1,
 		postStatusKey:                         1,
+		postStartDateKey:                      1,
+		postEndDateKey:                        1,
+		postWorkPeriodsKey:                    1,
 		createdKey:                            1,
 		concat(postOffersKey, vendorEmailKey): 1,
 		concat(postAcceptedOffersKey, vendorEmailKey): 1,
@@ -289,22 +536,51 @@ func FetchSinglePostByVendor(postID, vendorEmail string) (*types.Post, error) {
 	return post, err
 }
 
+// requirementSpecsFilter returns the conditions a post's requirement specifications for an item need to satisfy
+// so that the vendor can physically serve it i.e every specification is either not required or is met by the vendor's specifications
+func requirementSpecsFilter(item string, specs types.Specs) []types.M {
+	conditions := make([]types.M, 0)
+	for field, value := range specs.NumericFields() {
+		key := concat(postRequirementSpecsKey, item, field)
+		conditions = append(conditions, types.M{
+			"$or": []types.M{
+				{key: types.M{"$exists": false}},
+				{key: types.M{"$lte": value}},
+			},
+		})
+	}
+	fuelTypeKey := concat(postRequirementSpecsKey, item, "fuel_type")
+	conditions = append(conditions, types.M{
+		"$or": []types.M{
+			{fuelTypeKey: types.M{"$exists": false}},
+			{fuelTypeKey: specs.FuelType},
+		},
+	})
+	return conditions
+}
+
 // FetchPostsByVendor returns all open posts based on the vendor's inventory
+// Only those posts are returned whose requirement specifications for the lookup items are met by the vendor's specifications
+// If a geo query is provided then only the posts around the given point are returned along with their distance from it
+// Each post also holds the overall rating of its owner which is null if the owner hasn't been rated yet
 // TODO: be sure to add to projections on addition of sensitive fields to posts
-func FetchPostsByVendor(vendorEmail string, pageNumber int64, lookupItems []string) ([]types.M, error) {
+func FetchPostsByVendor(vendorEmail string, pageNumber int64, lookupItems []string, vendorSpecs types.EquipmentSpecs, query *types.PostQuery) ([]types.M, error) {
 	searchArray := make([]types.M, 0)
 	for _, item := range lookupItems {
-		searchArray = append(searchArray, types.M{
+		conditions := append(requirementSpecsFilter(item, vendorSpecs[item]), types.M{
 			concat(postRequirementsKey, item): types.M{
 				"$gt": 0,
 			},
 		})
+		searchArray = append(searchArray, types.M{
+			"$and": conditions,
+		})
 	}
 	vendorEmailKey, err := utils.Encrypt(vendorEmail)
 	if err != nil {
 		return []types.M{}, err
 	}
-	return fetchDocs(postCollection, types.M{
+	filter := types.M{
 		postStatusKey: types.OPEN,
 		"$or":         searchArray,
 		concat(postOffersKey, vendorEmailKey): types.M{
@@ -313,13 +589,100 @@ func FetchPostsByVendor(vendorEmail string, pageNumber int64, lookupItems []stri
 		concat(postAcceptedOffersKey, vendorEmailKey): types.M{
 			"$exists": false,
 		},
-	}, options.Find().SetSort(types.M{
-		updatedKey: -1,
-	}).SetSkip(postPageSize*pageNumber).SetLimit(postPageSize).SetProjection(types.M{
+	}
+	projection := types.M{
 		postOwnerKey:          0,
 		postOffersKey:         0,
 		postAcceptedOffersKey: 0,
-	}))
+		postHistoryKey:        0,
+		postWorkPeriodsKey:    0,
+		postOwnerProfileKey:   0,
+	}
+
+	pipeline := []types.M{
+		{"$match": filter},
+	}
+	if geo := query.Geo; geo != nil {
+		// $geoNear makes use of the 2dsphere index on the post's location and has to be the first stage of the pipeline
+		geoNear := types.M{
+			"near": types.M{
+				"type":        "Point",
+				"coordinates": []float64{geo.Longitude, geo.Latitude},
+			},
+			"key":                postLocationKey,
+			"spherical":          true,
+			"query":              filter,
+			"distanceField":      postDistanceKey,
+			"distanceMultiplier": 1.0 / metresPerKm,
+		}
+		if geo.RadiusKm > 0 {
+			geoNear["maxDistance"] = geo.RadiusKm * metresPerKm
+		}
+		pipeline = []types.M{
+			{"$geoNear": geoNear},
+		}
+	}
+	pipeline = append(pipeline, clientRatingStages()...)
+	if query.MinClientRating > 0 {
+		pipeline = append(pipeline, types.M{
+			"$match": types.M{
+				postClientRatingKey: types.M{"$gte": query.MinClientRating},
+			},
+		})
+	}
+	switch query.Sort {
+	case types.SortByDistance:
+		// $geoNear already returns the posts sorted by distance
+	case types.SortByClientRating:
+		pipeline = append(pipeline, types.M{"$sort": bson.D{
+			{Key: postClientRatingKey, Value: -1},
+			{Key: updatedKey, Value: -1},
+		}})
+	default:
+		pipeline = append(pipeline, types.M{"$sort": types.M{updatedKey: -1}})
+	}
+	pipeline = append(pipeline,
+		types.M{"$skip": postPageSize * pageNumber},
+		types.M{"$limit": postPageSize},
+		types.M{"$project": projection},
+	)
+	return aggregate(postCollection, pipeline)
+}
+
+// clientRatingStages returns the aggregation stages which add the overall rating of a post's owner to the post
+// The rating is null if the owner hasn't been rated yet
+func clientRatingStages() []types.M {
+	rating := "$$rating."
+	return []types.M{
+		{"$lookup": types.M{
+			"from":         userCollectionKey,
+			"localField":   postOwnerKey,
+			"foreignField": userEmailKey,
+			"as":           postOwnerProfileKey,
+		}},
+		{"$addFields": types.M{
+			postClientRatingKey: types.M{
+				"$let": types.M{
+					"vars": types.M{
+						"rating": types.M{"$arrayElemAt": []interface{}{concat("$"+postOwnerProfileKey, userRatingKey), 0}},
+					},
+					"in": types.M{
+						"$cond": []interface{}{
+							types.M{"$gt": []interface{}{rating + "count", 0}},
+							types.M{"$round": []interface{}{
+								types.M{"$divide": []interface{}{
+									types.M{"$add": []string{rating + "punctuality", rating + "equipment_condition", rating + "communication"}},
+									types.M{"$multiply": []interface{}{3, rating + "count"}},
+								}},
+								2,
+							}},
+							nil,
+						},
+					},
+				},
+			},
+		}},
+	}
 }
 
 // FetchOfferedPostsByVendor returns all posts the vendor has made an offer to
@@ -340,8 +703,11 @@ func FetchOfferedPostsByVendor(vendorEmail string) ([]types.M, error) {
 		postDescriptionKey:                    1,
 		postLocationKey:                       1,
 		postRequirementsKey:                   1,
+		postRequirementSpecsKey:               1,
 		postStatusKey:                         1,
 		postOwnerNameKey:                      1,
+		postStartDateKey:                      1,
+		postEndDateKey:                        1,
 		createdKey:                            1,
 		concat(postOffersKey, vendorEmailKey): 1,
 	}))
@@ -355,7 +721,7 @@ func FetchContractedPostsByVendor(vendorEmail string) ([]types.M, error) {
 	}
 	return fetchDocs(postCollection, types.M{
 		postStatusKey: types.M{
-			"$in": []string{types.OPEN, types.ONGOING},
+			"$in": []string{types.OPEN, types.ONGOING, types.COMPLETION_REQUESTED},
 		},
 		concat(postAcceptedOffersKey, vendorEmailKey): types.M{
 			"$exists": true,
@@ -363,14 +729,17 @@ func FetchContractedPostsByVendor(vendorEmail string) ([]types.M, error) {
 	}, options.Find().SetSort(types.M{
 		updatedKey: -1,
 	}).SetProjection(types.M{ // TODO : update these fields as more information is added to posts
-		postNameKey:         1,
-		postDescriptionKey:  1,
-		postLocationKey:     1,
-		postRequirementsKey: 1,
-		postStatusKey:       1,
-		postOwnerNameKey:    1,
-		postCommentsKey:     1,
-		createdKey:          1,
+		postNameKey:             1,
+		postDescriptionKey:      1,
+		postLocationKey:         1,
+		postRequirementsKey:     1,
+		postRequirementSpecsKey: 1,
+		postStatusKey:           1,
+		postOwnerNameKey:        1,
+		postCommentsKey:         1,
+		postStartDateKey:        1,
+		postEndDateKey:          1,
+		createdKey:              1,
 		concat(postAcceptedOffersKey, vendorEmailKey): 1,
 	}))
 }
@@ -438,11 +807,11 @@ func FetchPostNameAndOwner(postID string) (string, string, error) {
 	return contract.Name, contract.Owner, nil
 }
 
-// FetchPostOffersAndRequirementsAndStatus returns a post's offers (both accepted and pending) and requirements as well as its status
-func FetchPostOffersAndRequirementsAndStatus(postID string) (string, map[string]types.Offer, types.Inventory, error) {
+// FetchPostOffersAndRequirementsAndStatusAndWindow returns a post's pending offers and requirements as well as its status and time window
+func FetchPostOffersAndRequirementsAndStatusAndWindow(postID string) (string, map[string]types.Offer, types.Inventory, int64, int64, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return "", nil, types.Inventory{}, err
+		return "", nil, types.Inventory{}, 0, 0, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
@@ -451,18 +820,26 @@ func FetchPostOffersAndRequirementsAndStatus(postID string) (string, map[string]
 	post := &types.Post{}
 	err = postCollection.FindOne(ctx, types.M{
 		primaryKey: docID,
-	}, options.FindOne().SetProjection(types.M{postOffersKey: 1, postRequirementsKey: 1, postStatusKey: 1})).Decode(post)
+	}, options.FindOne().SetProjection(types.M{
+		postOffersKey:       1,
+		postRequirementsKey: 1,
+		postStatusKey:       1,
+		postStartDateKey:    1,
+		postEndDateKey:      1,
+	})).Decode(post)
 	if err != nil {
-		return "", nil, types.Inventory{}, err
+		return "", nil, types.Inventory{}, 0, 0, err
 	}
-	return post.Status, post.Offers, post.Requirements, nil
+	start, end := post.Window()
+	return post.Status, post.Offers, post.Requirements, start, end, nil
 }
 
-// FetchPostRequirementsAndStatus returns the requirements of a post
-func FetchPostRequirementsAndStatus(postID string) (string, *types.Inventory, error) {
+// FetchPostRequirementDetails returns a post with only its requirements, requirement specifications, status and time window
+// These are required for validating the offers made to the post
+func FetchPostRequirementDetails(postID string) (*types.Post, error) {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
@@ -470,11 +847,14 @@ func FetchPostRequirementsAndStatus(postID string) (string, *types.Inventory, er
 	post := &types.Post{}
 	err = postCollection.FindOne(ctx, types.M{
 		primaryKey: docID,
-	}, options.FindOne().SetProjection(types.M{postRequirementsKey: 1, postStatusKey: 1})).Decode(post)
-	if err != nil {
-		return "", nil, err
-	}
-	return post.Status, &post.Requirements, nil
+	}, options.FindOne().SetProjection(types.M{
+		postRequirementsKey:     1,
+		postRequirementSpecsKey: 1,
+		postStatusKey:           1,
+		postStartDateKey:        1,
+		postEndDateKey:          1,
+	})).Decode(post)
+	return post, err
 }
 
 // FetchPostAcceptedOffersAndStatusAndName returns the accepted offers of a post as well as its status and its name
@@ -516,50 +896,160 @@ func FetchPostAcceptedOffersAndName(postID string) (map[string]types.Offer, stri
 }
 
 // AcceptOffer accepts an offer made by a vendor on a post
+// and reserves the offer's contents from the vendor's inventory for the post's time window within a single transaction
 // This operation is invoked by the client who is the owner of the post
 // The param "offerKey" is key holding the offer in the post
 // It is the vendor's email address encrypted with AES-256
-func AcceptOffer(postID, offerKey string, offer types.Offer) error {
+// The param "offer" holds the part of the offer being accepted, the param "remainder" is the part which stays pending
+// The pending offer is removed if there is no remainder
+// ErrConflict is returned if the offer was changed, the post's requirements can no longer accommodate the offer
+// or the vendor's inventory got booked by another post in the meantime
+func AcceptOffer(postID, offerKey, vendorEmail string, offer types.Offer, remainder *types.Offer, start, end int64) error {
 	docID, err := primitive.ObjectIDFromHex(postID)
 	if err != nil {
 		return err
 	}
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		return acceptOffer(ctx, docID, offerKey, vendorEmail, offer, remainder, start, end)
+	})
+}
 
+// AcceptOffers accepts a bundle of offers on a post within a single transaction, either all of them are accepted or none
+// ErrConflict is returned along with the key of the offer which could not be accepted, see AcceptOffer
+func AcceptOffers(postID string, bundle []types.BundledOffer, start, end int64) (string, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return types.EMPTY, err
+	}
+	failed := types.EMPTY
+	err = withTransaction(func(ctx mongo.SessionContext) error {
+		for _, item := range bundle {
+			failed = item.OfferKey
+			if err := acceptOffer(ctx, docID, item.OfferKey, item.VendorEmail, item.Accepted, item.Remainder, start, end); err != nil {
+				return err
+			}
+		}
+		failed = types.EMPTY
+		return nil
+	})
+	return failed, err
+}
+
+// acceptOffer moves an offer into the accepted offers of a post and reserves its contents within the given transaction
+func acceptOffer(ctx mongo.SessionContext, docID primitive.ObjectID, offerKey, vendorEmail string, offer types.Offer, remainder *types.Offer, start, end int64) error {
+	// The offer must still be the same as the one validated by the client
+	// and the post's current requirements must be able to accommodate it
 	filter := types.M{
+		primaryKey:    docID,
+		postStatusKey: types.OPEN,
+		concat(postOffersKey, offerKey, offerTimestampKey): offer.Created,
+	}
+	for key, value := range inventoryIncrements(offer.Content, 1, postRequirementsKey) {
+		filter[key] = types.M{
+			"$gte": value,
+		}
+	}
+
+	// Units accepted earlier keep their price, hence an offer priced differently can't be merged into the accepted offer
+	current := &types.Post{}
+	if err := postCollection.FindOne(ctx, types.M{
 		primaryKey: docID,
+	}, options.FindOne().SetProjection(types.M{
+		concat(postAcceptedOffersKey, offerKey): 1,
+	})).Decode(current); err != nil {
+		return conflictOnNoDocuments(err)
+	}
+	if accepted, ok := current.AcceptedOffers[offerKey]; ok {
+		if err := accepted.CheckMerge(offer); err != nil {
+			return ErrPriceMismatch
+		}
 	}
 
 	// Make a map for incrementing the post's accepted offers and decrementing the posts's current requirements
 	// This is handy if a vendor makes offer twice and both are accepted
 	// This section would combine the 2 individual offers into a single accepted offer
-	changeMap := make(map[string]int64)
-
-	offerValues := reflect.ValueOf(offer.Content)
-	offerKeys := reflect.TypeOf(offer.Content)
-
-	for i := 0; i < offerValues.NumField(); i++ {
-		value := offerValues.Field(i).Int()
-		if value == 0 {
-			continue
-		}
-		acceptedOfferIncrementKey := concat(postAcceptedOffersKey, offerKey, offerContentKey, offerKeys.Field(i).Name)
-		requirementsDecrementKey := concat(postRequirementsKey, offerKeys.Field(i).Name)
-		changeMap[acceptedOfferIncrementKey] = value
-		changeMap[requirementsDecrementKey] = value * -1
+	changeMap := inventoryIncrements(offer.Content, 1, postAcceptedOffersKey, offerKey, offerContentKey)
+	for key, value := range inventoryIncrements(offer.Content, -1, postRequirementsKey) {
+		changeMap[key] = value
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
-	defer cancel()
-	return postCollection.FindOneAndUpdate(ctx, filter, types.M{
+	update := types.M{
 		"$unset": types.M{
 			concat(postOffersKey, offerKey): "",
 		},
 		"$inc": changeMap,
+		// Merged offers are billed from their first acceptance
+		"$min": types.M{
+			concat(postAcceptedOffersKey, offerKey, offerAcceptedKey): time.Now().Unix(),
+		},
 		// TODO : Update $set as more and more fields are added to offer schema
 		"$set": types.M{
 			concat(postAcceptedOffersKey, offerKey, offerNameKey):      offer.Name,
 			concat(postAcceptedOffersKey, offerKey, offerTimestampKey): offer.Created,
-			concat(postAcceptedOffersKey, offerKey, offerRateKey):      offer.Rate,
 		},
-	}).Err()
+	}
+
+	// The prices of a merged offer are the same for the items accepted earlier, see CheckMerge
+	// Legacy offers priced as a whole hold a rate instead
+	for key, price := range offer.Prices {
+		update["$set"].(types.M)[concat(postAcceptedOffersKey, offerKey, offerPricesKey, key)] = price
+	}
+	if offer.Currency != types.EMPTY {
+		update["$set"].(types.M)[concat(postAcceptedOffersKey, offerKey, offerCurrencyKey)] = offer.Currency
+	}
+	if offer.Rate != 0 {
+		update["$set"].(types.M)[concat(postAcceptedOffersKey, offerKey, offerRateKey)] = offer.Rate
+	}
+
+	// The specifications of a merged offer are the latest ones declared by the vendor
+	if len(offer.Specs) > 0 {
+		update["$set"].(types.M)[concat(postAcceptedOffersKey, offerKey, offerSpecsKey)] = offer.Specs
+	}
+
+	// The remainder of a partially accepted offer is a new pending offer, hence stale acceptances of the original fail
+	if remainder != nil {
+		delete(update, "$unset")
+		remainder.Created = time.Now().Unix()
+		if remainder.Created <= offer.Created {
+			remainder.Created = offer.Created + 1
+		}
+		update["$set"].(types.M)[concat(postOffersKey, offerKey)] = remainder
+	}
+
+	if err := postCollection.FindOneAndUpdate(ctx, filter, update).Err(); err != nil {
+		return conflictOnNoDocuments(err)
+	}
+	if err := lockVendorReservations(ctx, vendorEmail); err != nil {
+		return err
+	}
+	available, _, _, err := fetchVendorAvailability(ctx, vendorEmail, start, end)
+	if err != nil {
+		return err
+	}
+	if offer.Content.Exceeds(*available) {
+		return ErrConflict
+	}
+	return reserveVendorInventory(ctx, vendorEmail, docID, start, end, offer.Content)
+}
+
+// FetchPostParticipants returns the name, status, owner and accepted offers of a post
+func FetchPostParticipants(postID string) (*types.Post, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	post := &types.Post{}
+	err = postCollection.FindOne(ctx, types.M{
+		primaryKey: docID,
+	}, options.FindOne().SetProjection(types.M{
+		postNameKey:           1,
+		postStatusKey:         1,
+		postOwnerKey:          1,
+		postOwnerNameKey:      1,
+		postAcceptedOffersKey: 1,
+	})).Decode(post)
+	return post, err
 }
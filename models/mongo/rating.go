@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ratingCollectionKey is the collection for all ratings given after the completion of posts
+	ratingCollectionKey = "ratings"
+
+	// ratingPostIDKey is the key holding the ID of the post a rating was given for
+	ratingPostIDKey = "post_id"
+
+	// ratingRaterKey is the key holding the email ID of the user giving the rating
+	ratingRaterKey = "rater"
+
+	// ratingRateeKey is the key holding the email ID of the user being rated
+	ratingRateeKey = "ratee"
+
+	// userRatingKey is the key holding the aggregate of the ratings received by a user
+	userRatingKey = "rating"
+
+	// duplicateKeyCode is the error code returned by mongoDB on the violation of a unique index
+	duplicateKeyCode = 11000
+)
+
+var ratingCollection = db.Collection(ratingCollectionKey)
+
+// ErrAlreadyRated is returned when a user rates another user more than once for the same post
+var ErrAlreadyRated = errors.New("User has already been rated for this post")
+
+// isDuplicateKeyError checks whether an error is caused by the violation of a unique index
+func isDuplicateKeyError(err error) bool {
+	if writeErr, ok := err.(mongo.WriteException); ok {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateRating inserts a rating and adds its scores to the aggregate of the ratee in a single transaction
+func CreateRating(rating *types.Rating) error {
+	rating.Created = time.Now().Unix()
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		if _, err := ratingCollection.InsertOne(ctx, rating); err != nil {
+			return err
+		}
+		_, err := userCollection.UpdateOne(ctx, types.M{
+			userEmailKey: rating.Ratee,
+		}, types.M{
+			"$inc": types.M{
+				concat(userRatingKey, "count"):               1,
+				concat(userRatingKey, "punctuality"):         rating.Scores.Punctuality,
+				concat(userRatingKey, "equipment_condition"): rating.Scores.EquipmentCondition,
+				concat(userRatingKey, "communication"):       rating.Scores.Communication,
+			},
+		})
+		return err
+	})
+	if isDuplicateKeyError(err) {
+		return ErrAlreadyRated
+	}
+	return err
+}
+
+// FetchRatingsByRatee returns all ratings received by a user, latest first
+func FetchRatingsByRatee(email string) ([]types.M, error) {
+	return fetchDocs(ratingCollection, types.M{
+		ratingRateeKey: email,
+	}, options.Find().SetSort(types.M{
+		createdKey: -1,
+	}).SetProjection(types.M{
+		ratingRaterKey: 0,
+		ratingRateeKey: 0,
+	}))
+}
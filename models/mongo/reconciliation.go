@@ -0,0 +1,233 @@
+package mongo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// expectedReservation is a reservation derived from an accepted offer on a post which is yet to be completed or deleted
+type expectedReservation struct {
+	content    types.Inventory
+	start, end int64
+}
+
+// fetchExpectedReservations derives the reservations of all vendors from the accepted offers on posts which are yet to be completed or deleted
+// in the form of <vendor email>:<post ID>:<reservation>
+func fetchExpectedReservations(ctx context.Context) (map[string]map[primitive.ObjectID]expectedReservation, []types.UnreadableOffer, error) {
+	cursor, err := postCollection.Find(ctx, types.M{
+		postStatusKey: types.M{
+			"$in": []string{types.OPEN, types.ONGOING, types.COMPLETION_REQUESTED},
+		},
+	}, options.Find().SetProjection(types.M{
+		postAcceptedOffersKey: 1,
+		postStartDateKey:      1,
+		postEndDateKey:        1,
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+	posts := make([]types.Post, 0)
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, nil, err
+	}
+
+	expected := make(map[string]map[primitive.ObjectID]expectedReservation)
+	unreadable := make([]types.UnreadableOffer, 0)
+	for _, post := range posts {
+		start, end := post.Window()
+		for offerKey, offer := range post.AcceptedOffers {
+			vendorEmail, err := utils.Decrypt(offerKey)
+			if err != nil {
+				unreadable = append(unreadable, types.UnreadableOffer{
+					PostID:   post.ID,
+					OfferKey: offerKey,
+				})
+				continue
+			}
+			if expected[vendorEmail] == nil {
+				expected[vendorEmail] = make(map[primitive.ObjectID]expectedReservation)
+			}
+			expected[vendorEmail][post.ID] = expectedReservation{
+				content: offer.Content,
+				start:   start,
+				end:     end,
+			}
+		}
+	}
+	return expected, unreadable, nil
+}
+
+// fetchRecordedReservations returns all reservations in the ledger in the form of <vendor email>:<post ID>:<reservation>
+func fetchRecordedReservations(ctx context.Context) (map[string]map[primitive.ObjectID]types.Reservation, error) {
+	cursor, err := reservationCollection.Find(ctx, types.M{})
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]types.Reservation, 0)
+	if err := cursor.All(ctx, &reservations); err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]map[primitive.ObjectID]types.Reservation)
+	for _, reservation := range reservations {
+		if recorded[reservation.Vendor] == nil {
+			recorded[reservation.Vendor] = make(map[primitive.ObjectID]types.Reservation)
+		}
+		recorded[reservation.Vendor][reservation.PostID] = reservation
+	}
+	return recorded, nil
+}
+
+// correctReservations rewrites the ledger of a single vendor as per its drifts within a transaction
+func correctReservations(vendorEmail string, drifts []types.ReservationDrift) error {
+	return withTransaction(func(ctx mongo.SessionContext) error {
+		if err := lockVendorReservations(ctx, vendorEmail); err != nil {
+			return err
+		}
+		for _, drift := range drifts {
+			// No accepted offer exists for the reservation, hence its an orphan
+			if drift.Expected.IsEmpty() {
+				if err := releaseReservation(ctx, vendorEmail, drift.PostID); err != nil {
+					return err
+				}
+				continue
+			}
+			_, err := reservationCollection.UpdateOne(ctx, types.M{
+				reservationVendorKey: vendorEmail,
+				reservationPostIDKey: drift.PostID,
+			}, types.M{
+				"$set": types.M{
+					reservationContentKey: drift.Expected,
+					reservationStartKey:   drift.ExpectedWindow[0],
+					reservationEndKey:     drift.ExpectedWindow[1],
+				},
+				"$setOnInsert": types.M{
+					createdKey: time.Now().Unix(),
+				},
+			}, options.Update().SetUpsert(true))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReconcileInventories rebuilds the expected reservations of every vendor from the accepted offers on posts which are yet to be completed or deleted
+// and compares them against the reservation ledger, every vendor holding either is reported along with its expected and recorded free inventory
+// If apply is true then the ledger is corrected to match the accepted offers, the vendors' declared inventories are never changed
+// The correction is refused with ErrReservationsNotMigrated till the vendors' inventories have been migrated into reservations
+func ReconcileInventories(apply bool) (*types.ReconciliationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), transactionTimeout*time.Second)
+	defer cancel()
+
+	if apply {
+		migrated, err := reservationsMigrated(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !migrated {
+			return nil, ErrReservationsNotMigrated
+		}
+	}
+
+	expected, unreadable, err := fetchExpectedReservations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := fetchRecordedReservations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vendors := make([]string, 0, len(expected)+len(recorded))
+	for vendorEmail := range expected {
+		vendors = append(vendors, vendorEmail)
+	}
+	for vendorEmail := range recorded {
+		if _, ok := expected[vendorEmail]; !ok {
+			vendors = append(vendors, vendorEmail)
+		}
+	}
+	sort.Strings(vendors)
+
+	report := &types.ReconciliationReport{
+		Vendors:          make([]types.InventoryReconciliation, 0),
+		UnreadableOffers: unreadable,
+		MissingVendors:   make([]string, 0),
+		Applied:          apply,
+	}
+
+	for _, vendorEmail := range vendors {
+		drifts := make([]types.ReservationDrift, 0)
+		expectedReserved, recordedReserved := types.Inventory{}, types.Inventory{}
+
+		for postID, reservation := range expected[vendorEmail] {
+			expectedReserved = expectedReserved.Add(reservation.content)
+			current, ok := recorded[vendorEmail][postID]
+			if ok && current.Content.Equal(reservation.content) && current.Start == reservation.start && current.End == reservation.end {
+				continue
+			}
+			drift := types.ReservationDrift{
+				PostID:         postID,
+				Expected:       reservation.content,
+				ExpectedWindow: [2]int64{reservation.start, reservation.end},
+			}
+			if ok {
+				drift.Recorded = current.Content
+				drift.RecordedWindow = [2]int64{current.Start, current.End}
+			}
+			drifts = append(drifts, drift)
+		}
+		for postID, reservation := range recorded[vendorEmail] {
+			recordedReserved = recordedReserved.Add(reservation.Content)
+			if _, ok := expected[vendorEmail][postID]; ok {
+				continue
+			}
+			drifts = append(drifts, types.ReservationDrift{
+				PostID:         postID,
+				Recorded:       reservation.Content,
+				RecordedWindow: [2]int64{reservation.Start, reservation.End},
+			})
+		}
+
+		capacity, err := fetchVendorInventory(ctx, vendorEmail)
+		missing := err == ErrNoDocuments
+		if err != nil && !missing {
+			return nil, err
+		}
+		if missing {
+			report.MissingVendors = append(report.MissingVendors, vendorEmail)
+		}
+		if capacity == nil {
+			capacity = &types.Inventory{}
+		}
+
+		report.Vendors = append(report.Vendors, types.InventoryReconciliation{
+			Vendor:       vendorEmail,
+			Inventory:    *capacity,
+			ExpectedFree: capacity.Subtract(expectedReserved),
+			RecordedFree: capacity.Subtract(recordedReserved),
+			Drifts:       drifts,
+		})
+		if len(drifts) > 0 {
+			report.Drifted++
+		}
+
+		// Reservations of vendors who no longer exist can't be locked, they are left for manual review
+		if apply && len(drifts) > 0 && !missing {
+			if err := correctReservations(vendorEmail, drifts); err != nil {
+				return nil, err
+			}
+			utils.LogInfo("Mongo-Reconciliation-1", "Corrected %d reservations of vendor %s", len(drifts), vendorEmail)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,340 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// reservationCollectionKey is the collection for the ledger of inventories reserved by vendors for posts
+	reservationCollectionKey = "reservations"
+
+	// reservationVendorKey is the key holding the email of the vendor whose inventory is reserved
+	reservationVendorKey = "vendor"
+
+	// reservationPostIDKey is the key holding the ID of the post for which the inventory is reserved
+	reservationPostIDKey = "post_id"
+
+	// reservationContentKey is the key holding the reserved inventory items
+	reservationContentKey = "content"
+
+	// reservationStartKey is the key holding the start of the reservation window
+	reservationStartKey = "start"
+
+	// reservationEndKey is the key holding the end of the reservation window
+	reservationEndKey = "end"
+
+	// reservationsMigrationID is the ID of the document in the counters collection recording that the vendors'
+	// inventories were migrated from the remaining stock into the total capacity
+	reservationsMigrationID = "reservations_migration"
+)
+
+var reservationCollection = db.Collection(reservationCollectionKey)
+
+// ErrInventoryReserved is returned when a vendor's inventory is reduced below the amount reserved for accepted offers
+var ErrInventoryReserved = errors.New("Inventory cannot be reduced below the amount reserved for accepted offers")
+
+// ErrReservationsMigrated is returned when the vendors' inventories are migrated into reservations more than once
+var ErrReservationsMigrated = errors.New("Vendor inventories have already been migrated into reservations")
+
+// ErrReservationsNotMigrated is returned when the reservations are reconciled before the vendors' inventories are migrated
+var ErrReservationsNotMigrated = errors.New("Vendor inventories have to be migrated into reservations before the reservations can be corrected, run migrate-reservations first")
+
+// lockVendorReservations bumps the reservation version of a vendor within a transaction
+// Reservations are separate documents, hence two transactions reserving the same vendor's inventory would not conflict
+// on their own. Writing to the vendor's document makes them conflict so that one of them is retried with the other's
+// reservation taken into account thereby making overbooking impossible
+func lockVendorReservations(ctx context.Context, vendorEmail string) error {
+	return conflictOnNoDocuments(userCollection.FindOneAndUpdate(ctx, types.M{
+		userEmailKey: vendorEmail,
+	}, types.M{
+		"$inc": types.M{
+			userReservationVersionKey: 1,
+		},
+	}).Err())
+}
+
+// reserveVendorInventory books the contents of an accepted offer from the vendor's inventory for the time window of a post
+// Multiple accepted offers of a vendor on the same post are merged into a single reservation
+func reserveVendorInventory(ctx context.Context, vendorEmail string, postID primitive.ObjectID, start, end int64, content types.Inventory) error {
+	filter := types.M{
+		reservationVendorKey: vendorEmail,
+		reservationPostIDKey: postID,
+	}
+	_, err := reservationCollection.UpdateOne(ctx, filter, types.M{
+		"$inc": inventoryIncrements(content, 1, reservationContentKey),
+		"$set": types.M{
+			reservationStartKey: start,
+			reservationEndKey:   end,
+		},
+		"$setOnInsert": types.M{
+			createdKey: time.Now().Unix(),
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// releaseReservation releases the inventory reserved by a vendor for a post
+func releaseReservation(ctx context.Context, vendorEmail string, postID primitive.ObjectID) error {
+	_, err := reservationCollection.DeleteOne(ctx, types.M{
+		reservationVendorKey: vendorEmail,
+		reservationPostIDKey: postID,
+	})
+	return err
+}
+
+// releasePostReservations releases the inventories of all vendors reserved for a post
+// This is done when the post is either COMPLETED or DELETED
+func releasePostReservations(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := reservationCollection.DeleteMany(ctx, types.M{
+		reservationPostIDKey: postID,
+	})
+	return err
+}
+
+// fetchVendorReservations returns all reservations of a vendor which overlap with the time window [start, end)
+func fetchVendorReservations(ctx context.Context, vendorEmail string, start, end int64) ([]types.Reservation, error) {
+	cursor, err := reservationCollection.Find(ctx, types.M{
+		reservationVendorKey: vendorEmail,
+		reservationStartKey: types.M{
+			"$lt": end,
+		},
+		reservationEndKey: types.M{
+			"$gt": start,
+		},
+	}, options.Find().SetSort(types.M{
+		reservationStartKey: 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]types.Reservation, 0)
+	err = cursor.All(ctx, &reservations)
+	return reservations, err
+}
+
+// fetchVendorAvailability returns the vendor's inventory which is free throughout the time window [start, end)
+// along with the vendor's declared inventory and the maximum amount reserved within that window
+func fetchVendorAvailability(ctx context.Context, vendorEmail string, start, end int64) (*types.Inventory, *types.Inventory, *types.Inventory, error) {
+	capacity, err := fetchVendorInventory(ctx, vendorEmail)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Vendor hasn't initialized its inventory yet
+	if capacity == nil {
+		capacity = &types.Inventory{}
+	}
+	reservations, err := fetchVendorReservations(ctx, vendorEmail, start, end)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	reserved := types.PeakReservedInventory(reservations, start, end)
+	available := capacity.Subtract(reserved)
+	return &available, capacity, &reserved, nil
+}
+
+// FetchVendorReservations returns all reservations of a vendor which overlap with the time window [start, end)
+func FetchVendorReservations(vendorEmail string, start, end int64) ([]types.Reservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return fetchVendorReservations(ctx, vendorEmail, start, end)
+}
+
+// FetchVendorAvailability returns the vendor's inventory which is free throughout the time window [start, end)
+// along with the vendor's declared inventory and the maximum amount reserved within that window
+func FetchVendorAvailability(vendorEmail string, start, end int64) (*types.Inventory, *types.Inventory, *types.Inventory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return fetchVendorAvailability(ctx, vendorEmail, start, end)
+}
+
+// replaceVendorInventory replaces the entire inventory of a vendor within a transaction and returns the previous one
+// The specifications are replaced by the given ones unless they are nil, those of items which are no longer
+// in the inventory are dropped
+// ErrInventoryReserved is returned if any item falls below the amount reserved from now onwards
+func replaceVendorInventory(ctx mongo.SessionContext, vendorEmail string, inventory types.Inventory, specs types.EquipmentSpecs) (*types.Inventory, error) {
+	vendor := &types.User{}
+	if err := userCollection.FindOne(ctx, types.M{
+		userEmailKey: vendorEmail,
+		userRoleKey:  types.Vendor,
+	}, options.FindOne().SetProjection(types.M{
+		userInventoryKey:      1,
+		userInventorySpecsKey: 1,
+	})).Decode(vendor); err != nil {
+		return nil, err
+	}
+	previous := &types.Inventory{}
+	if vendor.Inventory != nil {
+		previous = vendor.Inventory
+	}
+
+	if err := lockVendorReservations(ctx, vendorEmail); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	reservations, err := fetchVendorReservations(ctx, vendorEmail, now, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	if types.PeakReservedInventory(reservations, now, math.MaxInt64).Exceeds(inventory) {
+		return nil, ErrInventoryReserved
+	}
+
+	if specs == nil {
+		specs = vendor.InventorySpecs
+	}
+	// No need to put fields with zero values into mongoDB, wastage of space
+	_, err = userCollection.UpdateOne(ctx, types.M{
+		userEmailKey: vendorEmail,
+	}, types.M{
+		"$set": types.M{
+			userInventoryKey:      types.Inventory{}.Add(inventory),
+			userInventorySpecsKey: specs.Subset(inventory),
+		},
+	})
+	return previous, err
+}
+
+// ReplaceVendorInventory replaces the entire inventory of a vendor and returns the previous one
+func ReplaceVendorInventory(vendorEmail string, inventory types.Inventory) (*types.Inventory, error) {
+	var previous *types.Inventory
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		var err error
+		previous, err = replaceVendorInventory(ctx, vendorEmail, inventory, nil)
+		return err
+	})
+	return previous, err
+}
+
+// reservationsMigrated checks whether the vendors' inventories have been migrated into total capacities and reservations
+func reservationsMigrated(ctx context.Context) (bool, error) {
+	count, err := counterCollection.CountDocuments(ctx, types.M{
+		primaryKey: reservationsMigrationID,
+	})
+	return count > 0, err
+}
+
+// migrateReservation adds the part of a vendor's accepted offer which isn't reserved yet back to its inventory and reserves it for the post
+// Offers accepted before reservations decremented the inventory instead, even if more units were accepted and reserved later on
+// Returns false if the offer has already been reserved entirely
+func migrateReservation(vendorEmail string, postID primitive.ObjectID, start, end int64, content types.Inventory) (bool, error) {
+	migrated := false
+	err := withTransaction(func(ctx mongo.SessionContext) error {
+		migrated = false
+		if err := lockVendorReservations(ctx, vendorEmail); err != nil {
+			return err
+		}
+		reservation := &types.Reservation{}
+		err := reservationCollection.FindOne(ctx, types.M{
+			reservationVendorKey: vendorEmail,
+			reservationPostIDKey: postID,
+		}).Decode(reservation)
+		if err != nil && err != ErrNoDocuments {
+			return err
+		}
+		unreserved := make(types.Inventory)
+		for key, value := range content.Subtract(reservation.Content) {
+			if value > 0 {
+				unreserved[key] = value
+			}
+		}
+		if unreserved.IsEmpty() {
+			return nil
+		}
+		if _, err := userCollection.UpdateOne(ctx, types.M{
+			userEmailKey: vendorEmail,
+		}, types.M{
+			"$inc": inventoryIncrements(unreserved, 1, userInventoryKey),
+		}); err != nil {
+			return err
+		}
+		migrated = true
+		return reserveVendorInventory(ctx, vendorEmail, postID, start, end, unreserved)
+	})
+	return migrated, err
+}
+
+// MigrateReservations converts the vendors' inventories from the remaining stock into the total capacity
+// Accepting an offer used to decrement the vendor's inventory until the post was completed. For every accepted offer
+// on a post which is yet to be completed or deleted, the accepted quantities which aren't reserved are added back to
+// the vendor's inventory and reserved for the post's window instead
+// The migration is recorded once every accepted offer could be read, after which it can't be run again as the later
+// drift between the reservations and the accepted offers is no longer caused by the decrements. The reconciliation
+// can only be applied after the migration, else it would reserve the decremented units without adding them back
+// Returns the number of offers migrated along with the offers whose key could not be decrypted
+func MigrateReservations() (int, []types.UnreadableOffer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), transactionTimeout*time.Second)
+	defer cancel()
+
+	migrated, err := reservationsMigrated(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if migrated {
+		return 0, nil, ErrReservationsMigrated
+	}
+
+	cursor, err := postCollection.Find(ctx, types.M{
+		postStatusKey: types.M{
+			"$in": []string{types.OPEN, types.ONGOING, types.COMPLETION_REQUESTED},
+		},
+	}, options.Find().SetProjection(types.M{
+		postAcceptedOffersKey: 1,
+		postStartDateKey:      1,
+		postEndDateKey:        1,
+	}))
+	if err != nil {
+		return 0, nil, err
+	}
+	posts := make([]types.Post, 0)
+	if err := cursor.All(ctx, &posts); err != nil {
+		return 0, nil, err
+	}
+
+	count := 0
+	unreadable := make([]types.UnreadableOffer, 0)
+	for _, post := range posts {
+		start, end := post.Window()
+		for offerKey, offer := range post.AcceptedOffers {
+			if offer.Content.IsEmpty() {
+				continue
+			}
+			vendorEmail, err := utils.Decrypt(offerKey)
+			if err != nil {
+				unreadable = append(unreadable, types.UnreadableOffer{
+					PostID:   post.ID,
+					OfferKey: offerKey,
+				})
+				continue
+			}
+			ok, err := migrateReservation(vendorEmail, post.ID, start, end, offer.Content)
+			if err != nil {
+				return count, unreadable, err
+			}
+			if ok {
+				count++
+			}
+		}
+	}
+
+	// Unreadable offers are left for another run once their keys are fixed
+	if len(unreadable) > 0 {
+		return count, unreadable, nil
+	}
+	_, err = counterCollection.UpdateOne(ctx, types.M{
+		primaryKey: reservationsMigrationID,
+	}, types.M{
+		"$setOnInsert": types.M{
+			createdKey: time.Now().Unix(),
+		},
+	}, options.Update().SetUpsert(true))
+	return count, unreadable, err
+}
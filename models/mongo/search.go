@@ -0,0 +1,264 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reverie/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// searchCollectionKey is the collection for the searches saved by vendors
+	searchCollectionKey = "saved_searches"
+
+	// searchVendorKey is the key holding the email ID of the vendor who saved a search
+	searchVendorKey = "vendor"
+
+	// searchItemsKey is the key holding the items looked up by a saved search
+	searchItemsKey = "items"
+
+	// searchNameKey is the key holding the name of a saved search
+	searchNameKey = "name"
+
+	// searchMinQuantityKey is the key holding the least quantity of an item required by the posts matching a saved search
+	searchMinQuantityKey = "min_quantity"
+
+	// searchRegionKey is the key holding the region of a saved search
+	searchRegionKey = "region"
+
+	// searchAreaKey is the key holding the radius around a point a saved search is restricted to
+	searchAreaKey = "area"
+
+	// searchDailyDigestKey is the key denoting whether the matches of a saved search are emailed daily
+	searchDailyDigestKey = "daily_digest"
+
+	// alertCollectionKey is the collection for the posts which matched the saved searches
+	alertCollectionKey = "search_alerts"
+
+	// alertSearchIDKey is the key holding the ID of the saved search an alert was raised for
+	alertSearchIDKey = "search_id"
+
+	// alertPostIDKey is the key holding the ID of the post which matched a saved search
+	alertPostIDKey = "post_id"
+
+	// alertVendorKey is the key holding the email ID of the vendor an alert was raised for
+	alertVendorKey = "vendor"
+
+	// alertDigestKey is the key denoting whether an alert awaits the vendor's daily email digest
+	alertDigestKey = "digest"
+
+	// alertPostKey is the key holding the post of an alert when joined with the posts
+	alertPostKey = "post"
+)
+
+var (
+	searchCollection = db.Collection(searchCollectionKey)
+	alertCollection  = db.Collection(alertCollectionKey)
+)
+
+// CreateSavedSearch stores a vendor's saved search
+func CreateSavedSearch(search *types.SavedSearch) (interface{}, error) {
+	return insertOne(searchCollection, search)
+}
+
+// FetchSavedSearches returns all searches saved by a vendor, latest first
+func FetchSavedSearches(vendorEmail string) ([]types.M, error) {
+	return fetchDocs(searchCollection, types.M{
+		searchVendorKey: vendorEmail,
+	}, options.Find().SetSort(types.M{
+		createdKey: -1,
+	}))
+}
+
+// UpdateSavedSearch replaces the criteria of a vendor's saved search
+func UpdateSavedSearch(searchID, vendorEmail string, search *types.SavedSearch) error {
+	docID, err := primitive.ObjectIDFromHex(searchID)
+	if err != nil {
+		return err
+	}
+	filter := types.M{
+		primaryKey:      docID,
+		searchVendorKey: vendorEmail,
+	}
+	return updateOne(searchCollection, filter, types.M{
+		searchNameKey:        search.Name,
+		searchItemsKey:       search.Items,
+		searchMinQuantityKey: search.MinQuantity,
+		searchRegionKey:      search.Region,
+		searchAreaKey:        search.Area,
+		searchDailyDigestKey: search.DailyDigest,
+		updatedKey:           search.Updated,
+	})
+}
+
+// DeleteSavedSearch deletes a vendor's saved search along with its alerts
+func DeleteSavedSearch(searchID, vendorEmail string) error {
+	docID, err := primitive.ObjectIDFromHex(searchID)
+	if err != nil {
+		return err
+	}
+	res, err := deleteOne(searchCollection, types.M{
+		primaryKey:      docID,
+		searchVendorKey: vendorEmail,
+	})
+	if err != nil {
+		return err
+	}
+	if res.(*mongo.DeleteResult).DeletedCount == 0 {
+		return ErrNoDocuments
+	}
+	_, err = deleteMany(alertCollection, types.M{
+		alertSearchIDKey: docID,
+	})
+	return err
+}
+
+// FetchSearchesForPost returns the saved searches looking up atleast one of the items required by a post
+// The rest of the criteria are checked by the caller
+func FetchSearchesForPost(post *types.Post) ([]types.SavedSearch, error) {
+	items := make([]string, 0, len(post.Requirements))
+	for key, value := range post.Requirements {
+		if value > 0 {
+			items = append(items, key)
+		}
+	}
+	searches := make([]types.SavedSearch, 0)
+	if len(items) == 0 {
+		return searches, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	cursor, err := searchCollection.Find(ctx, types.M{
+		searchItemsKey: types.M{"$in": items},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// RecordSearchAlerts raises alerts for the saved searches matched by a new or updated post
+// and notifies every vendor once per post irrespective of the number of its searches matched
+func RecordSearchAlerts(post *types.Post, searches []types.SavedSearch, updated bool) error {
+	if len(searches) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	models := make([]mongo.WriteModel, 0, len(searches))
+	names := make(map[string][]string)
+	vendors := make([]string, 0)
+	for _, search := range searches {
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(types.M{
+			alertSearchIDKey: search.ID,
+			alertPostIDKey:   post.ID,
+		}).SetUpdate(types.M{
+			"$set": types.SearchAlert{
+				SearchID:   search.ID,
+				SearchName: search.Name,
+				Vendor:     search.Vendor,
+				PostID:     post.ID,
+				PostName:   post.Name,
+				Digest:     search.DailyDigest,
+				Updated:    now,
+			},
+		}).SetUpsert(true))
+		if _, ok := names[search.Vendor]; !ok {
+			vendors = append(vendors, search.Vendor)
+		}
+		names[search.Vendor] = append(names[search.Vendor], fmt.Sprintf("\"%s\"", search.Name))
+	}
+	if _, err := bulkUpsert(alertCollection, models); err != nil {
+		return err
+	}
+
+	event := "been posted"
+	if updated {
+		event = "been updated"
+	}
+	notifications := make([]interface{}, 0, len(vendors))
+	for _, vendor := range vendors {
+		notifications = append(notifications, types.Notification{
+			PostID:   post.ID,
+			Recipent: vendor,
+			Type:     types.INFO,
+			Message:  fmt.Sprintf("Post %s matching your saved search %s has %s", post.Name, joinNames(names[vendor]), event),
+			Read:     false,
+			Created:  now,
+		})
+	}
+	_, err := insertMany(notificationCollection, notifications)
+	return err
+}
+
+// joinNames joins the names of saved searches into a human readable list
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	list := names[0]
+	for _, name := range names[1 : len(names)-1] {
+		list += ", " + name
+	}
+	return list + " and " + names[len(names)-1]
+}
+
+// FetchPendingDigests returns the alerts awaiting the daily email digest grouped by vendor
+// Alerts whose post is no longer OPEN are left out as the vendors can't make offers on them anymore
+func FetchPendingDigests() ([]types.SearchDigest, error) {
+	pipeline := []types.M{
+		{"$match": types.M{alertDigestKey: true}},
+		{"$lookup": types.M{
+			"from":         postCollectionKey,
+			"localField":   alertPostIDKey,
+			"foreignField": primaryKey,
+			"as":           alertPostKey,
+		}},
+		{"$match": types.M{concat(alertPostKey, postStatusKey): types.OPEN}},
+		{"$project": types.M{alertPostKey: 0}},
+		{"$sort": types.M{updatedKey: -1}},
+		{"$group": types.M{
+			"_id":    "$" + alertVendorKey,
+			"alerts": types.M{"$push": "$$ROOT"},
+		}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	cursor, err := alertCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	digests := make([]types.SearchDigest, 0)
+	if err := cursor.All(ctx, &digests); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// MarkDigested unsets the digest flag of a vendor's alerts which have been emailed
+// Alerts refreshed after the digest was compiled stay pending for the next one
+func MarkDigested(digest *types.SearchDigest) error {
+	ids := make([]primitive.ObjectID, 0, len(digest.Alerts))
+	var latest int64
+	for _, alert := range digest.Alerts {
+		ids = append(ids, alert.PostID)
+		if alert.Updated > latest {
+			latest = alert.Updated
+		}
+	}
+	_, err := updateMany(alertCollection, types.M{
+		alertVendorKey: digest.Vendor,
+		alertPostIDKey: types.M{"$in": ids},
+		alertDigestKey: true,
+		updatedKey:     types.M{"$lte": latest},
+	}, types.M{
+		alertDigestKey: false,
+	})
+	return err
+}
@@ -0,0 +1,270 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reverie/types"
+	"github.com/reverie/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// shipmentCollectionKey is the collection for all shipments of equipment to posts
+	shipmentCollectionKey = "shipments"
+
+	// shipmentPostIDKey is the key holding the ID of the post a shipment is delivered to
+	shipmentPostIDKey = "post_id"
+
+	// shipmentOfferKey is the key holding the key of the accepted offer being shipped
+	shipmentOfferKey = "offer_key"
+
+	// shipmentVendorKey is the key holding the email ID of the vendor shipping the equipment
+	shipmentVendorKey = "vendor"
+
+	// shipmentClientKey is the key holding the email ID of the client receiving the equipment
+	shipmentClientKey = "client"
+
+	// shipmentStatusKey is the key holding the status of a shipment
+	shipmentStatusKey = "status"
+
+	// shipmentContentKey is the key holding the equipment being shipped
+	shipmentContentKey = "content"
+
+	// shipmentVendorNameKey is the key holding the name of the vendor shipping the equipment
+	shipmentVendorNameKey = "vendor_name"
+
+	// shipmentETAKey is the key holding the estimated timestamp of delivery
+	shipmentETAKey = "eta"
+
+	// shipmentProofKey is the key holding the proof of delivery
+	shipmentProofKey = "proof_of_delivery"
+
+	// shipmentReceiptKey is the key holding the timestamp at which the client confirmed the receipt
+	shipmentReceiptKey = "receipt_confirmed"
+
+	// shipmentHistoryKey is the key holding the status changes of a shipment
+	shipmentHistoryKey = "history"
+)
+
+var shipmentCollection = db.Collection(shipmentCollectionKey)
+
+// scheduleShipments creates a SCHEDULED shipment for every accepted offer of a post which doesn't have one yet
+// When a halted post is re-activated the shipments which are still SCHEDULED take the latest accepted quantities
+// and the CANCELLED ones are scheduled again, the shipments which have left the vendor are left untouched
+// Offers whose key can't be decrypted are logged and skipped so that they don't block the activation of the post
+func scheduleShipments(ctx context.Context, post *types.Post, actor string) error {
+	now := time.Now().Unix()
+	for offerKey, offer := range post.AcceptedOffers {
+		vendorEmail, err := utils.Decrypt(offerKey)
+		if err != nil {
+			utils.LogError("Mongo-Shipment-1", fmt.Errorf("Could not decrypt offer key %s of post %s, its shipment was not scheduled: %v", offerKey, post.ID.Hex(), err))
+			continue
+		}
+		res, err := shipmentCollection.UpdateOne(ctx, types.M{
+			shipmentPostIDKey: post.ID,
+			shipmentOfferKey:  offerKey,
+			shipmentStatusKey: types.SCHEDULED,
+		}, types.M{
+			"$set": types.M{
+				shipmentContentKey:    offer.Content,
+				shipmentVendorNameKey: offer.Name,
+				updatedKey:            now,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount > 0 {
+			continue
+		}
+		res, err = shipmentCollection.UpdateOne(ctx, types.M{
+			shipmentPostIDKey: post.ID,
+			shipmentOfferKey:  offerKey,
+			shipmentStatusKey: types.CANCELLED,
+		}, types.M{
+			"$set": types.M{
+				shipmentContentKey:    offer.Content,
+				shipmentVendorNameKey: offer.Name,
+				shipmentStatusKey:     types.SCHEDULED,
+				updatedKey:            now,
+			},
+			"$push": types.M{
+				shipmentHistoryKey: types.ShipmentEvent{
+					Actor:     actor,
+					Status:    types.SCHEDULED,
+					Timestamp: now,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount > 0 {
+			continue
+		}
+		_, err = shipmentCollection.UpdateOne(ctx, types.M{
+			shipmentPostIDKey: post.ID,
+			shipmentOfferKey:  offerKey,
+		}, types.M{
+			"$setOnInsert": types.Shipment{
+				PostID:     post.ID,
+				OfferKey:   offerKey,
+				Vendor:     vendorEmail,
+				Client:     post.Owner,
+				VendorName: offer.Name,
+				Content:    offer.Content,
+				Status:     types.SCHEDULED,
+				History: []types.ShipmentEvent{
+					{
+						Actor:     actor,
+						Status:    types.SCHEDULED,
+						Timestamp: now,
+					},
+				},
+				Created: now,
+				Updated: now,
+			},
+		}, options.Update().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelShipment withdraws the shipment of an accepted offer which is rejected while its post is halted
+// A SCHEDULED shipment is deleted whereas one which is on its way is CANCELLED, delivered equipment is returned as usual
+func cancelShipment(ctx context.Context, postID primitive.ObjectID, offerKey, actor string) error {
+	if _, err := shipmentCollection.DeleteOne(ctx, types.M{
+		shipmentPostIDKey: postID,
+		shipmentOfferKey:  offerKey,
+		shipmentStatusKey: types.SCHEDULED,
+	}); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	_, err := shipmentCollection.UpdateOne(ctx, types.M{
+		shipmentPostIDKey: postID,
+		shipmentOfferKey:  offerKey,
+		shipmentStatusKey: types.M{
+			"$in": []string{types.DISPATCHED, types.IN_TRANSIT},
+		},
+	}, types.M{
+		"$set": types.M{
+			shipmentStatusKey: types.CANCELLED,
+			updatedKey:        now,
+		},
+		"$push": types.M{
+			shipmentHistoryKey: types.ShipmentEvent{
+				Actor:     actor,
+				Status:    types.CANCELLED,
+				Note:      "Offer was rejected by the client",
+				Timestamp: now,
+			},
+		},
+	})
+	return err
+}
+
+// FetchShipmentsByPost returns all shipments to a post
+func FetchShipmentsByPost(postID string) ([]types.M, error) {
+	docID, err := primitive.ObjectIDFromHex(postID)
+	if err != nil {
+		return nil, err
+	}
+	return fetchDocs(shipmentCollection, types.M{
+		shipmentPostIDKey: docID,
+	}, options.Find().SetSort(types.M{
+		createdKey: 1,
+	}).SetProjection(types.M{
+		shipmentVendorKey:                   0,
+		shipmentClientKey:                   0,
+		concat(shipmentHistoryKey, "actor"): 0,
+	}))
+}
+
+// FetchShipmentsByVendor returns all shipments of a vendor which are yet to be returned or cancelled, latest first
+func FetchShipmentsByVendor(vendorEmail string) ([]types.M, error) {
+	return fetchDocs(shipmentCollection, types.M{
+		shipmentVendorKey: vendorEmail,
+		shipmentStatusKey: types.M{
+			"$nin": []string{types.RETURNED, types.CANCELLED},
+		},
+	}, options.Find().SetSort(types.M{
+		updatedKey: -1,
+	}).SetProjection(types.M{
+		shipmentVendorKey:                   0,
+		shipmentClientKey:                   0,
+		concat(shipmentHistoryKey, "actor"): 0,
+	}))
+}
+
+// FetchShipment returns a single shipment given its id
+func FetchShipment(shipmentID string) (*types.Shipment, error) {
+	docID, err := primitive.ObjectIDFromHex(shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+
+	shipment := &types.Shipment{}
+	err = shipmentCollection.FindOne(ctx, types.M{
+		primaryKey: docID,
+	}).Decode(shipment)
+	return shipment, err
+}
+
+// UpdateShipment applies a vendor's update to a shipment
+// The current status is a part of the filter so that ErrConflict is returned if it was changed by someone else in the meantime
+func UpdateShipment(shipment *types.Shipment, actor string, update *types.ShipmentUpdate) error {
+	now := time.Now().Unix()
+	filter := types.M{
+		primaryKey:        shipment.ID,
+		shipmentStatusKey: shipment.Status,
+	}
+	updatePayload := types.M{
+		updatedKey: now,
+	}
+	if update.ETA != 0 {
+		updatePayload[shipmentETAKey] = update.ETA
+	}
+	if update.ProofOfDelivery != nil {
+		update.ProofOfDelivery.Timestamp = now
+		updatePayload[shipmentProofKey] = update.ProofOfDelivery
+	}
+	mongoUpdate := types.M{
+		"$set": updatePayload,
+	}
+	if update.Status != types.EMPTY {
+		updatePayload[shipmentStatusKey] = update.Status
+		mongoUpdate["$push"] = types.M{
+			shipmentHistoryKey: types.ShipmentEvent{
+				Actor:     actor,
+				Status:    update.Status,
+				Note:      update.Note,
+				Timestamp: now,
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return conflictOnNoDocuments(shipmentCollection.FindOneAndUpdate(ctx, filter, mongoUpdate).Err())
+}
+
+// ConfirmShipmentReceipt records the client's confirmation of having received a DELIVERED shipment
+func ConfirmShipmentReceipt(shipment *types.Shipment) error {
+	now := time.Now().Unix()
+	return conflictOnNoDocuments(updateOne(shipmentCollection, types.M{
+		primaryKey:         shipment.ID,
+		shipmentStatusKey:  types.DELIVERED,
+		shipmentReceiptKey: types.M{"$exists": false},
+	}, types.M{
+		shipmentReceiptKey: now,
+		updatedKey:         now,
+	}))
+}
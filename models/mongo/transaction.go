@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// transientTransactionLabel is the error label attached by mongoDB to transactions which can be retried
+// Ex:- write conflicts with another transaction modifying the same document
+const transientTransactionLabel = "TransientTransactionError"
+
+// labeledError is implemented by the driver's server errors which carry error labels
+// Ex:- mongo.CommandError, mongo.WriteException and mongo.BulkWriteException
+type labeledError interface {
+	error
+	HasErrorLabel(label string) bool
+}
+
+// withTransaction runs a set of operations inside a multi-document transaction
+// All operations within the callback must use the provided session context for them to be a part of the transaction
+// The transaction is retried by the driver on transient errors such as write conflicts, if it keeps conflicting
+// till the timeout is reached ErrConflict is returned
+// IMPORTANT : Transactions require mongoDB to be deployed as a replica set
+func withTransaction(callback func(sessionCtx mongo.SessionContext) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), transactionTimeout*time.Second)
+	defer cancel()
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, callback(sessionCtx)
+	}, opts)
+
+	if err == nil {
+		return nil
+	}
+	var labeled labeledError
+	if errors.As(err, &labeled) && labeled.HasErrorLabel(transientTransactionLabel) {
+		return ErrConflict
+	}
+	// The driver keeps retrying transient errors till the timeout, running out of it means the conflict persisted
+	if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+		return ErrConflict
+	}
+	return err
+}
+
+// conflictOnNoDocuments converts ErrNoDocuments into ErrConflict
+// Used for conditional updates whose filter no longer matches because of a concurrent modification
+func conflictOnNoDocuments(err error) error {
+	if err == ErrNoDocuments {
+		return ErrConflict
+	}
+	return err
+}
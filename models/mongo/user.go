@@ -2,14 +2,11 @@ package mongo
 
 import (
 	"context"
-	"errors"
-	"reflect"
+	"regexp"
 	"time"
 
 	"github.com/reverie/types"
-	"github.com/reverie/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -34,6 +31,21 @@ const (
 
 	// userVerifiedKey is the key denoting the whether the user is verified or not
 	userVerifiedKey = "verified"
+
+	// userInventorySpecsKey is the key denoting the specifications of the items in a vendor's inventory
+	userInventorySpecsKey = "inventory_specs"
+
+	// userSuspendedKey is the key denoting whether a user's account has been suspended
+	userSuspendedKey = "suspended"
+
+	// userCompanyKey is the key holding the company of a user
+	userCompanyKey = "company"
+
+	// userPageSize is the maximum number of users retrieved in one batch for admins
+	userPageSize = 30
+
+	// userReservationVersionKey is the key holding the number of times a vendor's reservations have been modified within transactions
+	userReservationVersionKey = "reservation_version"
 )
 
 // The link to the user collection
@@ -50,94 +62,6 @@ func UpdateUser(filter types.M, data interface{}) error {
 	return updateOne(userCollection, filter, data)
 }
 
-// InitVendorInventory initializes the vendor's inventory
-// Should be called only once per vendor and this call should be authorized by us
-func InitVendorInventory(vendorEmail string, inventory *types.Inventory) error {
-	filter := types.M{
-		userEmailKey: vendorEmail,
-	}
-	// Make a map for initializing the vendor's inventory
-	initMap := make(map[string]int64)
-
-	inventoryValues := reflect.ValueOf(*inventory)
-	inventoryKeys := reflect.TypeOf(*inventory)
-
-	for i := 0; i < inventoryValues.NumField(); i++ {
-		key := concat(userInventoryKey, inventoryKeys.Field(i).Name)
-		value := inventoryValues.Field(i).Int()
-		if value < 0 {
-			return errors.New("Vendor inventory values cannot be negative")
-		}
-		// No need to put fields with zero values into mongoDB, wastage of space
-		if value == 0 {
-			continue
-		}
-		initMap[key] = value
-	}
-
-	return updateOne(userCollection, filter, initMap)
-}
-
-// UpdateVendorInventoryOnAcceptance updates a vendor's inventory after their offer has been accepted on a post
-// This deducts the offer contents from the vendor's current inventory
-func UpdateVendorInventoryOnAcceptance(vendorEmail string, offer types.Inventory) error {
-	filter := types.M{
-		userEmailKey: vendorEmail,
-	}
-
-	// Make a map for decrementing the vendor's inventory
-	decrementMap := make(map[string]int64)
-
-	offerValues := reflect.ValueOf(offer)
-	offerKeys := reflect.TypeOf(offer)
-
-	for i := 0; i < offerValues.NumField(); i++ {
-		key := concat(userInventoryKey, offerKeys.Field(i).Name)
-		value := offerValues.Field(i).Int() * -1
-		if value == 0 {
-			continue
-		}
-		decrementMap[key] = value
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
-	defer cancel()
-
-	return userCollection.FindOneAndUpdate(ctx, filter, types.M{
-		"$inc": decrementMap,
-	}).Err()
-}
-
-// ReleaseSingleVendorInventory updates a vendor's inventory after their offer has been rejected from a post
-// The remainder inventory items are added back to the vendor's inventory pool
-func ReleaseSingleVendorInventory(vendorEmail string, offer types.Inventory) error {
-	filter := types.M{
-		userEmailKey: vendorEmail,
-	}
-
-	// Make a map for incrementing the vendor's inventory
-	incrementMap := make(map[string]int64)
-
-	offerValues := reflect.ValueOf(offer)
-	offerKeys := reflect.TypeOf(offer)
-
-	for i := 0; i < offerValues.NumField(); i++ {
-		key := concat(userInventoryKey, offerKeys.Field(i).Name)
-		value := offerValues.Field(i).Int()
-		if value == 0 {
-			continue
-		}
-		incrementMap[key] = value
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
-	defer cancel()
-
-	return userCollection.FindOneAndUpdate(ctx, filter, types.M{
-		"$inc": incrementMap,
-	}).Err()
-}
-
 // FetchUsers returns all users given their email ids
 func FetchUsers(emailList []string) ([]types.M, error) {
 	return fetchDocs(userCollection, types.M{
@@ -147,57 +71,6 @@ func FetchUsers(emailList []string) ([]types.M, error) {
 	}, options.Find())
 }
 
-// ReleaseVendorInventories releases inventories of all vendors bound to a job after it is marked as COMPLETED by the client
-// This set of inventory is then added back to their respective vendor's inventory pool
-func ReleaseVendorInventories(acceptedOffers map[string]types.Offer) error {
-	updates := make([]mongo.WriteModel, 0)
-
-	for offerKey, offer := range acceptedOffers {
-		vendorEmail, err := utils.Decrypt(offerKey)
-		// TODO : Mail us in this case, because this means a vendor's inventory did not get released properly
-		// Possible cause: Encryption key or nonce was changed in config.toml mid-production
-		if err != nil {
-			utils.LogError("kekw", err)
-			continue
-		}
-
-		// Make a map for incrementing the vendor's inventory
-		incrementMap := make(map[string]int64)
-
-		offerValues := reflect.ValueOf(offer.Content)
-		offerKeys := reflect.TypeOf(offer.Content)
-
-		for i := 0; i < offerValues.NumField(); i++ {
-			key := concat(userInventoryKey, offerKeys.Field(i).Name)
-			value := offerValues.Field(i).Int()
-			if value == 0 {
-				continue
-			}
-			incrementMap[key] = value
-		}
-
-		operation := mongo.NewUpdateOneModel()
-		operation.SetFilter(types.M{
-			userEmailKey: vendorEmail,
-		})
-		operation.SetUpdate(types.M{
-			"$inc": incrementMap,
-		})
-
-		updates = append(updates, operation)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
-	defer cancel()
-
-	result, err := userCollection.BulkWrite(ctx, updates)
-
-	// TODO : proper logging on job completion
-	utils.LogInfo("Released Inventories", "Released Inventories %v", *result)
-
-	return err
-}
-
 // UpdatePassword is an abstraction over UpdateOne which updates a user's password
 func UpdatePassword(email, newHashedPassword string) error {
 	filter := types.M{
@@ -224,19 +97,41 @@ func FetchSingleUser(email string, opts ...*options.FindOneOptions) (*types.User
 	return user, err
 }
 
+// fetchVendorInventory returns the inventory of a vendor
+func fetchVendorInventory(ctx context.Context, vendorEmail string) (*types.Inventory, error) {
+	user := &types.User{}
+	err := userCollection.FindOne(ctx, types.M{
+		userEmailKey: vendorEmail,
+	}, options.FindOne().SetProjection(types.M{userInventoryKey: 1})).Decode(user)
+	if err != nil {
+		return nil, err
+	}
+	return user.Inventory, nil
+}
+
 // FetchVendorInventory returns the inventory of a vendor
 func FetchVendorInventory(vendorEmail string) (*types.Inventory, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
 	defer cancel()
+	return fetchVendorInventory(ctx, vendorEmail)
+}
+
+// FetchVendorInventorySpecs returns the specifications of the items in a vendor's inventory
+func FetchVendorInventorySpecs(vendorEmail string) (types.EquipmentSpecs, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
 
 	user := &types.User{}
 	err := userCollection.FindOne(ctx, types.M{
 		userEmailKey: vendorEmail,
-	}, options.FindOne().SetProjection(types.M{userInventoryKey: 1})).Decode(user)
+	}, options.FindOne().SetProjection(types.M{userInventorySpecsKey: 1})).Decode(user)
 	if err != nil {
 		return nil, err
 	}
-	return user.Inventory, nil
+	if user.InventorySpecs == nil {
+		return types.EquipmentSpecs{}, nil
+	}
+	return user.InventorySpecs, nil
 }
 
 // FetchSingleUserWithoutPassword returns a user based on a email based filter without his/her password
@@ -271,3 +166,73 @@ func VerifyUserEmail(userID string) error {
 	}
 	return updateOne(userCollection, filter, updatePayload, nil)
 }
+
+// SearchUsers returns the users matching the query, latest first
+func SearchUsers(query *types.UserQuery, pageNumber int64) ([]types.M, error) {
+	filter := types.M{}
+	if query.Search != types.EMPTY {
+		pattern := primitive.Regex{
+			Pattern: regexp.QuoteMeta(query.Search),
+			Options: "i",
+		}
+		filter["$or"] = []types.M{
+			{userEmailKey: pattern},
+			{usernameKey: pattern},
+			{userCompanyKey: pattern},
+		}
+	}
+	if query.Role != types.EMPTY {
+		filter[userRoleKey] = query.Role
+	}
+	if query.Suspended {
+		filter[userSuspendedKey] = true
+	}
+	if query.Unverified {
+		filter[userVerifiedKey] = false
+	}
+	return fetchDocs(userCollection, filter, options.Find().SetSort(types.M{
+		primaryKey: -1,
+	}).SetSkip(userPageSize*pageNumber).SetLimit(userPageSize).SetProjection(types.M{
+		userPasswordKey: 0,
+	}))
+}
+
+// VerifyUser marks a user's email as verified given the email ID
+func VerifyUser(email string) error {
+	return updateOne(userCollection, types.M{
+		userEmailKey: email,
+	}, types.M{
+		userVerifiedKey: true,
+	})
+}
+
+// SetUserSuspended suspends or lifts the suspension of a user's account
+// Admins cannot be suspended
+func SetUserSuspended(email string, suspended bool) error {
+	filter := types.M{
+		userEmailKey: email,
+		userRoleKey: types.M{
+			"$ne": types.Admin,
+		},
+	}
+	update := types.M{
+		"$set": types.M{userSuspendedKey: true},
+	}
+	if !suspended {
+		update = types.M{
+			"$unset": types.M{userSuspendedKey: ""},
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+	defer cancel()
+	return userCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// IsUserSuspended checks whether a user's account has been suspended
+func IsUserSuspended(email string) (bool, error) {
+	count, err := countDocs(userCollection, types.M{
+		userEmailKey:     email,
+		userSuspendedKey: true,
+	})
+	return count > 0, err
+}
@@ -0,0 +1,58 @@
+package payments
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/reverie/utils"
+)
+
+// FakeToken is the token which makes the fake gateway decline a payment
+// Used for exercising the failure paths during development
+const FakeToken = "fake_decline"
+
+// fakeGateway is a local gateway for development which moves no money and approves every payment
+type fakeGateway struct {
+	counter int64
+}
+
+func init() {
+	Register(&fakeGateway{})
+}
+
+// Name returns the name of the fake gateway
+func (gateway *fakeGateway) Name() string {
+	return "fake"
+}
+
+// nextID generates an identifier for a fake transaction
+func (gateway *fakeGateway) nextID(prefix string) string {
+	return fmt.Sprintf("%s_%06d", prefix, atomic.AddInt64(&gateway.counter, 1))
+}
+
+// Charge approves the charge unless the fake decline token is used
+func (gateway *fakeGateway) Charge(request *Request) (string, error) {
+	if request.Token == FakeToken {
+		return "", ErrDeclined
+	}
+	id := gateway.nextID("fake_ch")
+	utils.LogInfo("Fake-Gateway-1", "Charged %d paise from %s for %s (%s)", request.Amount, request.Email, request.Reference, id)
+	return id, nil
+}
+
+// Refund approves every refund
+func (gateway *fakeGateway) Refund(chargeID string, amount int64) (string, error) {
+	id := gateway.nextID("fake_rf")
+	utils.LogInfo("Fake-Gateway-2", "Refunded %d paise of %s (%s)", amount, chargeID, id)
+	return id, nil
+}
+
+// Payout approves the payout unless the fake decline token is used
+func (gateway *fakeGateway) Payout(request *Request) (string, error) {
+	if request.Token == FakeToken {
+		return "", ErrDeclined
+	}
+	id := gateway.nextID("fake_po")
+	utils.LogInfo("Fake-Gateway-3", "Paid %d paise to %s for %s (%s)", request.Amount, request.Email, request.Reference, id)
+	return id, nil
+}
@@ -0,0 +1,69 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/reverie/configs"
+)
+
+// ErrDeclined is returned by a gateway when it refuses to process a payment
+var ErrDeclined = errors.New("Payment was declined by the payment gateway")
+
+// Request holds the details of a payment to be processed by a gateway
+type Request struct {
+	// Reference is our identifier for the payment, Ex:- the invoice number for charges
+	// Gateways should use it as an idempotency key
+	Reference string
+
+	// Email ID of the client being charged or the vendor being paid
+	Email string
+
+	// Amount in paise
+	Amount int64
+
+	Currency string
+
+	// Token identifies the payment method, it is issued by the gateway to the client application and is opaque to us
+	Token string
+}
+
+// Gateway is a payment service provider which moves money on our behalf
+// Every method returns the gateway's identifier of the transaction on success
+type Gateway interface {
+	// Name returns the name with which the gateway is configured
+	Name() string
+
+	// Charge collects money from a client
+	Charge(request *Request) (string, error)
+
+	// Refund returns the money collected in a charge to the client
+	Refund(chargeID string, amount int64) (string, error)
+
+	// Payout transfers money to a vendor
+	Payout(request *Request) (string, error)
+}
+
+var (
+	gateways = make(map[string]Gateway)
+	mutex    sync.RWMutex
+)
+
+// Register makes a gateway available for usage through the configuration
+func Register(gateway Gateway) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	gateways[gateway.Name()] = gateway
+}
+
+// Provider returns the gateway set in the configuration
+func Provider() (Gateway, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	gateway, ok := gateways[configs.PaymentsConfig.Provider]
+	if !ok {
+		return nil, fmt.Errorf("Payment gateway %q is not registered", configs.PaymentsConfig.Provider)
+	}
+	return gateway, nil
+}
@@ -13,7 +13,7 @@ import (
 // Owner Name of the post sent to vendors? In case the post creator is a middleman and the actual company is Tata or LNT. The post should be registered with the end client's details to garner brand value for easier transactions with vendors.
 // duration of the post and timeline ?
 // timeline is important http://localhost:8080/extra-pages/timeline
-// PART LEFT: Payment, Emails, Order Shipment Tracking
+// PART LEFT: Emails
 // TODO : No need to fetch all accepted offers, just the key (map reduce)
 // Password reset (proper)
 
@@ -21,11 +21,10 @@ import (
 // Contents :-
 // 1. For new accepted offer delete the pending one, then ask the vendor to make a new offer and accept the new one
 // 2. For adding contents to existing accepted offer, ask the vendor to make an offer with the remainder, then accept this offer, the new offer gets merged with the existing accepted offer
-// 3. No auditing, hence nothing can be done if accepted/pending offer is deleted (auditing required) or maybe just mark deleted ?
+// 3. If an accepted/pending offer is deleted by mistake, find its entry in the post's audit log and restore it, the client then has to accept it again
 
 // TODO : refactor mongo code
 // TODO : fix context messages and return error messages
-// TODO : add suggestion to client in frontend for activating post when all criteria has been satisfied
 // Add endpoint for fetching completed jobs for both client and vendor
 
 func newRouter() *fiber.App {
@@ -51,12 +50,19 @@ func newRouter() *fiber.App {
 		auth.Get("/reset-password/:email", c.ResetPassword)
 	}
 
-	client := router.Group("/client", m.JWT, m.IsClient)
+	client := router.Group("/client", m.JWT, m.IsClient, m.IsActive)
 	{
 		client.Get("", c.GetLoggedInUserInfo)
 		client.Put("/password", c.UpdatePassword)
 		client.Get("/post", c.FetchActivePostsByClient)
 		client.Post("/post", c.CreatePost)
+		client.Get("/invoice", c.FetchInvoicesByClient)
+		client.Get("/invoice/:number", c.FetchInvoiceByClient)
+		client.Get("/invoice/:number/html", c.RenderInvoiceHTML)
+		client.Get("/invoice/:number/pdf", c.RenderInvoicePDF)
+		client.Post("/invoice/:number/pay", c.PayInvoice)
+		client.Get("/balance", c.FetchClientBalance)
+		client.Get("/rating", c.FetchRatingsReceived)
 
 		// Actions which only the owner of a post can perform
 		postOwner := client.Group("/post/:id", m.IsPostOwner)
@@ -64,7 +70,15 @@ func newRouter() *fiber.App {
 			postOwner.Get("", c.FetchSinglePostByClient)
 			postOwner.Put("", c.UpdatePost)
 			postOwner.Delete("", c.DeletePost)
-
+			// Coverage of the requirements by the offers, "ready" suggests activating the post
+			// The "offers" query parameter simulates accepting the given pending offers
+			postOwner.Get("/coverage", c.FetchPostCoverage)
+			// Cheapest combination of pending offers covering the requirements, or the most within the "budget" query parameter
+			postOwner.Get("/recommendation", c.RecommendOffers)
+
+			// Accepts several offers at once, either all of them or none
+			// Registered ahead of the single offer routes so that "bundle" isn't taken for an offer key
+			postOwner.Patch("/offer/bundle/accept", c.AcceptOfferBundle)
 			postOwner.Patch("/offer/:key/accept", c.AcceptOffer)
 			postOwner.Put("/offer/:key/request-change", c.RequestOfferChange)
 			postOwner.Delete("/offer/:key/reject-accepted", c.RejectAcceptedOffer)
@@ -74,30 +88,111 @@ func newRouter() *fiber.App {
 			postOwner.Patch("/activate", c.ActivatePost)
 			postOwner.Patch("/deactivate", c.DeactivatePost)
 
-			// TODO: make clients/vendors fill a survey after completion?
-			// Restrict this route? client hits this, then we get a mail and approve and then only the process gets completed
-			// We shall hit the admin route
-			// This will generate the payment invoice and mail the client
+			// Client requests the completion, vendors confirm or dispute it and we get a mail
+			// We approve it through /admin/post/:id/complete which generates the invoice and mails the client
+			// Activating the post again withdraws the request
 			postOwner.Patch("/complete", c.MarkComplete)
+
+			// Once the post is COMPLETED, the client rates the vendors of the accepted offers
+			postOwner.Post("/rating/:key", c.RateVendor)
+
+			// Negotiation threads with the vendors of the offers on the post
+			postOwner.Get("/thread", c.FetchThreadsByPost)
+			postOwner.Get("/thread/:key", c.FetchThreadByClient)
+			postOwner.Post("/thread/:key", c.SendMessageByClient)
+
+			// Vendors invited on the creation of the post as their fleet matches its requirements
+			postOwner.Get("/invitations", c.FetchInvitations)
+
+			postOwner.Get("/shipment", c.FetchShipmentsByPost)
+			postOwner.Patch("/shipment/:shipment/confirm", c.ConfirmShipmentReceipt)
 		}
 	}
 
-	vendor := router.Group("/vendor", m.JWT, m.IsVendor)
+	vendor := router.Group("/vendor", m.JWT, m.IsVendor, m.IsActive)
 	{
 		vendor.Get("", c.GetLoggedInUserInfo)
-		vendor.Put("/inventory", c.InitializeInventory) // Restrict this, should only happen on our authorization
+		// Inventory and specification changes only take effect once approved by us
+		vendor.Put("/inventory", c.RequestInventoryChange)
+		vendor.Get("/inventory/request", c.FetchInventoryRequestsByVendor)
+		vendor.Put("/inventory/specs", c.UpdateInventorySpecs)
+		// Base location of the fleet, the vendor is invited to matching posts created near it
+		vendor.Put("/matching", c.UpdateMatchingPreferences)
 		vendor.Put("/password", c.UpdatePassword)
+		vendor.Get("/availability", c.FetchVendorAvailability)
+		vendor.Get("/balance", c.FetchVendorBalance)
+		vendor.Get("/rating", c.FetchRatingsReceived)
+		vendor.Get("/shipment", c.FetchShipmentsByVendor)
+		vendor.Patch("/shipment/:shipment", c.UpdateShipment)
+		// Saved searches alert the vendor about matching posts in-app and optionally in a daily email digest
+		vendor.Get("/search", c.FetchSavedSearches)
+		vendor.Post("/search", c.CreateSavedSearch)
+		vendor.Put("/search/:id", c.UpdateSavedSearch)
+		vendor.Delete("/search/:id", c.DeleteSavedSearch)
 		vendor.Get("/post", c.FetchPostsByVendor)
 		vendor.Get("/post/offered", c.FetchOfferedPostsByVendor)
 		vendor.Get("/post/contracted", c.FetchContractedPostsByVendor)
 		vendor.Get("/post/:id", c.FetchSinglePostByVendor)
 		// TODO: notify us so that we can contact the client directly in case he doesnt use the app
 		// Always make sure to update the entire body i.e the new body will be the new offer entirely (it replaces the old body, not updates it)
-		vendor.Put("/post/:id/offer/:rate", c.MakeOffer)
+		// Every offered item is priced individually per hour, day, trip or as a lump sum
+		vendor.Put("/post/:id/offer", c.MakeOffer)
+		// Legacy offers priced as a whole at a rate per day, the body holds only the offered inventory
+		vendor.Put("/post/:id/offer/:rate", c.MakeLegacyOffer)
 		vendor.Delete("/post/:id/retract", c.RetractOffer)
+		// Respond to a REQUEST_OFFER_CHANGE notification given by its ID
+		vendor.Patch("/offer/change/:id/accept", c.AcceptOfferChange)
+		vendor.Patch("/offer/change/:id/decline", c.DeclineOfferChange)
+		vendor.Patch("/post/:id/completion/confirm", c.ConfirmCompletion)
+		vendor.Patch("/post/:id/completion/dispute", c.DisputeCompletion)
+		vendor.Post("/post/:id/rating", c.RateClient)
+		vendor.Get("/post/:id/thread", c.FetchThreadByVendor)
+		vendor.Post("/post/:id/thread", c.SendMessageByVendor)
+	}
+
+	admin := router.Group("/admin", m.JWT, m.IsAdmin)
+	{
+		// Compare vendor reservations against accepted offers, POST corrects the drift
+		admin.Get("/reconcile/inventory", c.PreviewInventoryReconciliation)
+		admin.Post("/reconcile/inventory", c.ApplyInventoryReconciliation)
+
+		admin.Get("/catalog", c.FetchCatalog)
+		admin.Post("/catalog", c.CreateEquipment)
+		admin.Put("/catalog/:key", c.UpdateEquipment)
+
+		admin.Get("/balance", c.FetchLedgerBalances)
+		admin.Post("/payout", c.PayoutVendor)
+
+		admin.Get("/user", c.SearchUsers)
+		admin.Get("/user/:email", c.FetchUserByAdmin)
+		admin.Patch("/user/:email/verify", c.VerifyUser)
+		admin.Patch("/user/:email/suspend", c.SuspendUser)
+		admin.Patch("/user/:email/unsuspend", c.UnsuspendUser)
+		admin.Put("/user/:email/inventory", c.UpdateInventoryByAdmin)
+
+		admin.Get("/inventory/request", c.FetchInventoryRequests)
+		admin.Patch("/inventory/request/:id/approve", c.ApproveInventoryRequest)
+		admin.Patch("/inventory/request/:id/reject", c.RejectInventoryRequest)
+
+		admin.Get("/post/:id", c.FetchPostByAdmin)
+		admin.Patch("/post/:id/status", c.TransitionPostByAdmin)
+		admin.Patch("/post/:id/complete", c.CompletePostByAdmin)
+		admin.Get("/post/:id/thread", c.FetchThreadsByPost)
+		admin.Get("/post/:id/thread/:key", c.FetchThreadByAdmin)
+
+		// Every admin action above along with every post and offer mutation is recorded in the audit log
+		admin.Get("/audit", c.FetchAuditLog)
+		admin.Get("/audit/post/:id", c.FetchPostAuditLog)
+		admin.Get("/audit/user/:email", c.FetchUserAuditLog)
+		admin.Post("/audit/:id/restore", c.RestoreOffer)
+	}
+
+	catalog := router.Group("/catalog", m.JWT, m.IsActive)
+	{
+		catalog.Get("", c.FetchActiveCatalog)
 	}
 
-	notification := router.Group("/notification", m.JWT)
+	notification := router.Group("/notification", m.JWT, m.IsActive)
 	{
 		notification.Get("", c.FetchNotifications)
 		notification.Patch("/:id", c.ReadNotification)
@@ -99,8 +99,8 @@ func SendPostActivationEmail(post *types.Post, users []types.M) error {
 }
 
 // SendPostCompletionEmail sends an email notification denoting the end of a post
-// It also sends the amount and the bank account details to the post owner and CC's us
-func SendPostCompletionEmail(recipentEmail, recipentName, postName string, amount float64) error {
+// It also sends the invoice number, the amount and the bank account details to the post owner and CC's us
+func SendPostCompletionEmail(recipentEmail, recipentName string, invoice *types.Invoice) error {
 	message := mail.NewV3Mail()
 
 	message.SetFrom(anish)
@@ -116,9 +116,68 @@ func SendPostCompletionEmail(recipentEmail, recipentName, postName string, amoun
 	}
 	personalization.AddTos(tos...)
 	personalization.AddCCs(ccs...)
-	personalization.SetDynamicTemplateData("name", postName)
-	personalization.SetDynamicTemplateData("amount", fmt.Sprintf("%.2f", amount))
+	personalization.SetDynamicTemplateData("name", invoice.PostName)
+	personalization.SetDynamicTemplateData("invoice", invoice.Number)
+	personalization.SetDynamicTemplateData("amount", fmt.Sprintf("%.2f", invoice.Total))
 
 	message.AddPersonalizations(personalization)
 	return send(message)
 }
+
+// SendCompletionRequestEmail sends an email notification to us whenever a client marks a post as complete
+// The completion needs to be approved by us after which the invoice is generated
+func SendCompletionRequestEmail(post *types.Post) error {
+	content := fmt.Sprintf("%s (%s) has marked the post \"%s\" (%s) as complete with %d accepted offers.\n\n"+
+		"Vendors can confirm or dispute the completion, approve it through /admin/post/%s/complete once settled.",
+		post.OwnerName, post.Owner, post.Name, post.ID.Hex(), len(post.AcceptedOffers), post.ID.Hex())
+
+	message := mail.NewV3Mail()
+	message.SetFrom(anish)
+	message.Subject = fmt.Sprintf("Completion requested for post %s", post.Name)
+
+	personalization := mail.NewPersonalization()
+	personalization.AddTos(anish, goro)
+
+	message.AddPersonalizations(personalization)
+	message.AddContent(mail.NewContent("text/plain", content))
+	return send(message)
+}
+
+// SendInvitationEmail sends an email to a vendor inviting it to make an offer on a new post matching its fleet
+func SendInvitationEmail(post *types.Post, invitation *types.Invitation) error {
+	content := fmt.Sprintf("Hi %s,\n\nA new job \"%s\" matching your fleet has been posted %.1f km from your base.\n\n"+
+		"Your fleet can supply %s of its requirements. Make your offer at %s/post/%s",
+		invitation.VendorName, post.Name, invitation.DistanceKm, invitation.Matched.Describe(),
+		configs.Project.SendGrid.FrontendEndpoint, post.ID.Hex())
+
+	message := mail.NewV3Mail()
+	message.SetFrom(anish)
+	message.Subject = fmt.Sprintf("New job matching your fleet: %s", post.Name)
+
+	personalization := mail.NewPersonalization()
+	personalization.AddTos(mail.NewEmail(invitation.VendorName, invitation.Vendor))
+
+	message.AddPersonalizations(personalization)
+	message.AddContent(mail.NewContent("text/plain", content))
+	return send(message)
+}
+
+// SendSearchDigestEmail sends the daily digest of the open posts which matched a vendor's saved searches
+func SendSearchDigestEmail(vendorName, vendorEmail string, alerts []types.SearchAlert) error {
+	content := fmt.Sprintf("Hi %s,\n\n%d posts matched your saved searches since the last digest:\n", vendorName, len(alerts))
+	for _, alert := range alerts {
+		content += fmt.Sprintf("\n- %s (search \"%s\"): %s/post/%s", alert.PostName, alert.SearchName,
+			configs.Project.SendGrid.FrontendEndpoint, alert.PostID.Hex())
+	}
+
+	message := mail.NewV3Mail()
+	message.SetFrom(anish)
+	message.Subject = fmt.Sprintf("%d new posts matching your saved searches", len(alerts))
+
+	personalization := mail.NewPersonalization()
+	personalization.AddTos(mail.NewEmail(vendorName, vendorEmail))
+
+	message.AddPersonalizations(personalization)
+	message.AddContent(mail.NewContent("text/plain", content))
+	return send(message)
+}
@@ -0,0 +1,130 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Actions recorded in the audit log
+const (
+	// AuditVerifyUser denotes an admin verifying a user's account
+	AuditVerifyUser = "VERIFY_USER"
+
+	// AuditSuspendUser denotes an admin suspending a user's account
+	AuditSuspendUser = "SUSPEND_USER"
+
+	// AuditUnsuspendUser denotes an admin lifting the suspension of a user's account
+	AuditUnsuspendUser = "UNSUSPEND_USER"
+
+	// AuditUpdateInventory denotes an admin replacing a vendor's inventory
+	AuditUpdateInventory = "UPDATE_INVENTORY"
+
+	// AuditApproveInventory denotes an admin approving a vendor's inventory change request
+	AuditApproveInventory = "APPROVE_INVENTORY"
+
+	// AuditRejectInventory denotes an admin rejecting a vendor's inventory change request
+	AuditRejectInventory = "REJECT_INVENTORY"
+
+	// AuditTransitionPost denotes a post being moved into a new status by its owner or an admin
+	AuditTransitionPost = "TRANSITION_POST"
+
+	// AuditCompletePost denotes an admin approving the completion of a post
+	AuditCompletePost = "COMPLETE_POST"
+
+	// AuditUpdatePost denotes a client updating the details of a post
+	AuditUpdatePost = "UPDATE_POST"
+
+	// AuditMakeOffer denotes a vendor making or updating an offer on a post
+	AuditMakeOffer = "MAKE_OFFER"
+
+	// AuditRetractOffer denotes a vendor retracting its offer from a post
+	AuditRetractOffer = "RETRACT_OFFER"
+
+	// AuditAcceptOffer denotes a client accepting an offer on a post
+	AuditAcceptOffer = "ACCEPT_OFFER"
+
+	// AuditRejectAcceptedOffer denotes a client rejecting an offer which it had accepted
+	AuditRejectAcceptedOffer = "REJECT_ACCEPTED_OFFER"
+
+	// AuditRejectPendingOffer denotes a client rejecting an offer which is yet to be accepted
+	AuditRejectPendingOffer = "REJECT_PENDING_OFFER"
+
+	// AuditRequestOfferChange denotes a client asking a vendor to change its offer
+	AuditRequestOfferChange = "REQUEST_OFFER_CHANGE"
+
+	// AuditAcceptOfferChange denotes a vendor rewriting its offer as per a change requested by the client
+	AuditAcceptOfferChange = "ACCEPT_OFFER_CHANGE"
+
+	// AuditDeclineOfferChange denotes a vendor declining a change requested by the client
+	AuditDeclineOfferChange = "DECLINE_OFFER_CHANGE"
+
+	// AuditRestoreOffer denotes an admin restoring a removed offer from the audit log
+	AuditRestoreOffer = "RESTORE_OFFER"
+
+	// AuditCreateEquipment denotes an admin adding an equipment to the catalog
+	AuditCreateEquipment = "CREATE_EQUIPMENT"
+
+	// AuditUpdateEquipment denotes an admin updating an equipment in the catalog
+	AuditUpdateEquipment = "UPDATE_EQUIPMENT"
+
+	// AuditReconcileInventory denotes an admin rewriting the reservation ledger to match the accepted offers on active posts
+	// The vendors' declared inventories are left untouched, the report of the corrected drift is recorded as the outcome
+	AuditReconcileInventory = "RECONCILE_INVENTORY"
+
+	// AuditPayoutVendor denotes an admin paying out a vendor
+	AuditPayoutVendor = "PAYOUT_VENDOR"
+)
+
+// AuditEntry is a single record in the append-only audit log
+type AuditEntry struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Email ID and role of the user who performed the action
+	Actor     string `json:"actor" bson:"actor"`
+	ActorRole string `json:"actor_role" bson:"actor_role"`
+
+	Action string `json:"action" bson:"action"`
+
+	// Target identifies what the action was performed on i.e a user's email ID, a post's ID, an equipment key etc
+	Target string `json:"target,omitempty" bson:"target,omitempty"`
+
+	// Key of the offer and email ID of the vendor concerned by an offer action, the target being the post's ID
+	OfferKey string `json:"offer_key,omitempty" bson:"offer_key,omitempty"`
+	Vendor   string `json:"vendor,omitempty" bson:"vendor,omitempty"`
+
+	// Values of the target before and after the action
+	// For offer actions they are the offer before and after, Ex:- the pending offer and the accepted offer on acceptance
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+
+	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
+
+	// IP address from which the request was made
+	IP string `json:"ip" bson:"ip"`
+
+	Created int64 `json:"created" bson:"created"`
+}
+
+// AuditQuery holds the optional filters for searching the audit log
+type AuditQuery struct {
+	Actor  string
+	Action string
+	Target string
+
+	// User matches the entries in which the user is either the actor, the target or the vendor concerned
+	User string
+}
+
+// IsOfferRemoval checks if an entry records an offer being removed from a post, such offers can be restored
+func (entry *AuditEntry) IsOfferRemoval() bool {
+	switch entry.Action {
+	case AuditRetractOffer, AuditRejectPendingOffer, AuditRejectAcceptedOffer:
+		return true
+	}
+	return false
+}
+
+// AdminAction is the body of admin requests which change the status of a user or a post
+type AdminAction struct {
+	// Status to move a post to, only used for post transitions
+	Status string `json:"status,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,57 @@
+package types
+
+import "regexp"
+
+// equipmentKeyPattern restricts the equipment keys as they are used as field names within mongoDB documents
+// IMPORTANT : All keys have to be less than 28 characters due to mongoDB constraints
+var equipmentKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{0,27}$`)
+
+// LegacyEquipments are the equipments which were hard-coded into the inventory before the catalog was introduced
+// The catalog is seeded with these so that existing posts and inventories stay valid
+var LegacyEquipments = []Equipment{
+	{Key: "Truck", Name: "Truck", Unit: "vehicle", Category: "Transport", Active: true},
+	{Key: "Crane", Name: "Crane", Unit: "vehicle", Category: "Lifting", Active: true},
+	{Key: "Tanker", Name: "Tanker", Unit: "vehicle", Category: "Transport", Active: true},
+	{Key: "RoadRoller", Name: "Road Roller", Unit: "vehicle", Category: "Construction", Active: true},
+	{Key: "ForkLift", Name: "Fork Lift", Unit: "vehicle", Category: "Lifting", Active: true},
+	{Key: "BoomLifter", Name: "Boom Lifter", Unit: "vehicle", Category: "Lifting", Active: true},
+	{Key: "ManLifter", Name: "Man Lifter", Unit: "vehicle", Category: "Lifting", Active: true},
+	{Key: "HydraulicJack", Name: "Hydraulic Jack", Unit: "piece", Category: "Lifting", Active: true},
+	{Key: "Manpower", Name: "Manpower", Unit: "person", Category: "Labour", Active: true},
+}
+
+// Equipment stores a single type of equipment in the catalog
+// Requirements, offers and inventories can only hold items which are active in the catalog
+type Equipment struct {
+	// Key is the identifier of the equipment used within requirements, offers and inventories
+	Key string `json:"key" bson:"key" valid:"required"`
+
+	// Name is the display name of the equipment
+	Name string `json:"name" bson:"name" valid:"required"`
+
+	// Unit in which the equipment is counted, Ex:- "vehicle", "person"
+	Unit string `json:"unit" bson:"unit" valid:"required"`
+
+	// Category groups similar equipment together, Ex:- "Lifting", "Transport"
+	Category string `json:"category" bson:"category"`
+
+	// Active denotes whether the equipment can be used in new requirements, offers and inventories
+	Active bool `json:"active" bson:"active"`
+
+	Created int64 `json:"created" bson:"created"`
+	Updated int64 `json:"updated" bson:"updated"`
+}
+
+// IsValidEquipmentKey checks whether a key can be used for an equipment
+func IsValidEquipmentKey(key string) bool {
+	return equipmentKeyPattern.MatchString(key)
+}
+
+// EquipmentUpdate stores the information about an equipment which can be updated
+type EquipmentUpdate struct {
+	Name     string `json:"name,omitempty" bson:"name,omitempty"`
+	Unit     string `json:"unit,omitempty" bson:"unit,omitempty"`
+	Category string `json:"category,omitempty" bson:"category,omitempty"`
+	Active   *bool  `json:"active,omitempty" bson:"active,omitempty"`
+	Updated  int64  `json:"-" bson:"updated"`
+}
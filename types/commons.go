@@ -1,5 +1,11 @@
 package types
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // M is a shorthand notation for map[string]interface{}
 type M map[string]interface{}
 
@@ -23,16 +29,99 @@ const (
 	EMPTY = ""
 )
 
-// Inventory stores the items in a vendor's inventory
-// IMPORTANT : All names have to be less than 28 characters due to mongoDB constraints
-type Inventory struct {
-	Truck         int64 `json:"Truck,omitempty" bson:"Truck,omitempty"`
-	Crane         int64 `json:"Crane,omitempty" bson:"Crane,omitempty"`
-	Tanker        int64 `json:"Tanker,omitempty" bson:"Tanker,omitempty"`
-	RoadRoller    int64 `json:"RoadRoller,omitempty" bson:"RoadRoller,omitempty"`
-	ForkLift      int64 `json:"ForkLift,omitempty" bson:"ForkLift,omitempty"`
-	BoomLifter    int64 `json:"BoomLifter,omitempty" bson:"BoomLifter,omitempty"`
-	ManLifter     int64 `json:"ManLifter,omitempty" bson:"ManLifter,omitempty"`
-	HydraulicJack int64 `json:"HydraulicJack,omitempty" bson:"HydraulicJack,omitempty"`
-	Manpower      int64 `json:"Manpower,omitempty" bson:"Manpower,omitempty"`
+// Inventory stores the items in a vendor's inventory in the form of <equipment key>:<quantity>
+// The equipment keys are defined by the equipment catalog, see types.Equipment
+type Inventory map[string]int64
+
+// Validate checks whether all items in the inventory are a part of the given catalog and are non-negative
+func (inventory Inventory) Validate(catalog *Set) error {
+	for key, value := range inventory {
+		if !catalog.Contains(key) {
+			return fmt.Errorf("%s is not a part of the equipment catalog", key)
+		}
+		if value < 0 {
+			return fmt.Errorf("Quantity of %s cannot be negative", key)
+		}
+	}
+	return nil
+}
+
+// combine applies an operation item-wise on two inventories and returns the result
+// Items with zero values are omitted from the result
+func (inventory Inventory) combine(other Inventory, operation func(int64, int64) int64) Inventory {
+	result := Inventory{}
+	for key, value := range inventory {
+		if combined := operation(value, other[key]); combined != 0 {
+			result[key] = combined
+		}
+	}
+	for key, value := range other {
+		if _, ok := inventory[key]; ok {
+			continue
+		}
+		if combined := operation(0, value); combined != 0 {
+			result[key] = combined
+		}
+	}
+	return result
+}
+
+// Add returns the item-wise sum of two inventories
+func (inventory Inventory) Add(other Inventory) Inventory {
+	return inventory.combine(other, func(a, b int64) int64 { return a + b })
+}
+
+// Subtract returns the item-wise difference of two inventories
+func (inventory Inventory) Subtract(other Inventory) Inventory {
+	return inventory.combine(other, func(a, b int64) int64 { return a - b })
+}
+
+// Max returns the item-wise maximum of two inventories
+func (inventory Inventory) Max(other Inventory) Inventory {
+	return inventory.combine(other, func(a, b int64) int64 {
+		if a > b {
+			return a
+		}
+		return b
+	})
+}
+
+// Exceeds checks whether any item in the inventory is greater than the same item in the other inventory
+func (inventory Inventory) Exceeds(other Inventory) bool {
+	for key, value := range inventory {
+		if value > other[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal checks whether two inventories hold the same quantity of every item
+// Missing items are considered to be zero
+func (inventory Inventory) Equal(other Inventory) bool {
+	return !inventory.Exceeds(other) && !other.Exceeds(inventory)
+}
+
+// IsEmpty checks whether the inventory holds no items at all
+func (inventory Inventory) IsEmpty() bool {
+	for _, value := range inventory {
+		if value != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe lists the items of the inventory in the form of "<quantity> x <equipment key>" sorted by the keys
+func (inventory Inventory) Describe() string {
+	keys := make([]string, 0, len(inventory))
+	for key := range inventory {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, fmt.Sprintf("%d x %s", inventory[key], key))
+	}
+	return strings.Join(items, ", ")
 }
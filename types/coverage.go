@@ -0,0 +1,139 @@
+package types
+
+import (
+	"fmt"
+	"math"
+)
+
+// hoursPerDay is used for estimating the daily cost of equipment priced per hour, assuming round the clock engagement
+const hoursPerDay = 24
+
+// ItemCoverage denotes how far the offers on a post cover the requirement of a single equipment type
+type ItemCoverage struct {
+	// Required is the total quantity required by the post including the accepted quantity
+	Required int64 `json:"required"`
+	Accepted int64 `json:"accepted"`
+	Pending  int64 `json:"pending"`
+
+	// Short is the quantity which is yet to be accepted and Excess is the quantity accepted beyond the requirement
+	Short  int64 `json:"short"`
+	Excess int64 `json:"excess,omitempty"`
+}
+
+// Cost is the estimated cost of a set of offers
+type Cost struct {
+	// Daily is the cost per day of engagement, equipment priced per hour is assumed to be engaged round the clock
+	Daily float64 `json:"daily"`
+
+	// OneTime is the cost of the lump sum and per trip prices which doesn't depend on the duration
+	OneTime float64 `json:"one_time"`
+
+	// Estimated is the total cost for the scheduled duration of the post
+	// It is omitted for open-ended posts as their duration isn't known yet
+	Estimated *float64 `json:"estimated,omitempty"`
+
+	// OpenEnded denotes whether the post has no end date, only the daily and the one time costs apply then
+	OpenEnded bool `json:"open_ended,omitempty"`
+
+	Currency string `json:"currency"`
+}
+
+// Coverage is the coverage of a post's requirements by its offers along with the cost of the accepted offers
+// Simulated coverages treat the chosen pending offers as accepted
+type Coverage struct {
+	Items map[string]ItemCoverage `json:"items"`
+
+	// Ready denotes whether the requirements are fully covered by the accepted offers, i.e the post can be activated
+	Ready bool `json:"ready"`
+
+	Cost Cost `json:"cost"`
+}
+
+// DailyCost returns the cost of the offer per day of engagement and its one time cost
+// Legacy offers priced as a whole are charged their rate per day
+func (offer Offer) DailyCost() (float64, float64) {
+	daily, oneTime := offer.Rate, 0.0
+	for key, price := range offer.Prices {
+		quantity := float64(offer.Content[key])
+		switch price.Unit {
+		case PerHour:
+			daily += price.Amount * quantity * hoursPerDay
+		case PerDay:
+			daily += price.Amount * quantity
+		case PerTrip:
+			oneTime += price.Amount * quantity * float64(price.Trips)
+		case LumpSum:
+			oneTime += price.Amount
+		}
+	}
+	return daily, oneTime
+}
+
+// Coverage returns the coverage of the post's requirements as if the given pending offers were accepted as well
+// Requirements are decremented as offers are accepted, hence the total requirement is the sum of the remaining and the accepted quantities
+func (post *Post) Coverage(hypothetical []string) (*Coverage, error) {
+	accepted := make(Inventory)
+	pending := make(Inventory)
+	cost := Cost{
+		Currency: Currency,
+	}
+	addCost := func(offer Offer) {
+		daily, oneTime := offer.DailyCost()
+		cost.Daily += daily
+		cost.OneTime += oneTime
+	}
+	for _, offer := range post.AcceptedOffers {
+		accepted = accepted.Add(offer.Content)
+		addCost(offer)
+	}
+	for _, offer := range post.Offers {
+		pending = pending.Add(offer.Content)
+	}
+	required := post.Requirements.Add(accepted)
+
+	simulated := make(map[string]bool)
+	for _, offerKey := range hypothetical {
+		offer, ok := post.Offers[offerKey]
+		if !ok {
+			return nil, fmt.Errorf("Offer key %s doesnt exist in post %s", offerKey, post.ID.Hex())
+		}
+		if simulated[offerKey] {
+			continue
+		}
+		simulated[offerKey] = true
+		accepted = accepted.Add(offer.Content)
+		pending = pending.Subtract(offer.Content)
+		addCost(offer)
+	}
+
+	coverage := &Coverage{
+		Items: make(map[string]ItemCoverage),
+		Ready: true,
+	}
+	for key := range required.Add(accepted).Add(pending) {
+		item := ItemCoverage{
+			Required: required[key],
+			Accepted: accepted[key],
+			Pending:  pending[key],
+		}
+		if item.Accepted < item.Required {
+			item.Short = item.Required - item.Accepted
+			coverage.Ready = false
+		} else {
+			item.Excess = item.Accepted - item.Required
+		}
+		coverage.Items[key] = item
+	}
+
+	cost.Daily = roundCurrency(cost.Daily)
+	cost.OneTime = roundCurrency(cost.OneTime)
+	// Open-ended posts have no duration to estimate the total cost for
+	if start, end := post.Window(); end == math.MaxInt64 {
+		cost.OpenEnded = true
+	} else {
+		estimated := roundCurrency(cost.Daily*float64(billableDays(end-start)) + cost.OneTime)
+		cost.Estimated = &estimated
+	}
+	coverage.Cost = cost
+	return coverage, nil
+}
@@ -0,0 +1,91 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	// PENDING denotes an inventory change request which is yet to be reviewed by an admin
+	PENDING = "PENDING"
+
+	// APPROVED denotes an inventory change request which has been applied to the vendor's inventory
+	APPROVED = "APPROVED"
+
+	// REJECTED denotes an inventory change request which has been turned down by an admin
+	REJECTED = "REJECTED"
+)
+
+// InventoryChange denotes the change in the quantity of a single inventory item
+type InventoryChange struct {
+	Current   int64 `json:"current"`
+	Requested int64 `json:"requested"`
+}
+
+// SpecsChange denotes the change in the specifications of a single inventory item
+type SpecsChange struct {
+	Current   Specs `json:"current"`
+	Requested Specs `json:"requested"`
+}
+
+// InventoryRequest is a vendor's request to change its inventory or the specifications of its items
+// The vendor's inventory is only changed once an admin approves the request
+type InventoryRequest struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Email ID and name of the vendor who raised the request
+	Vendor     string `json:"vendor" bson:"vendor"`
+	VendorName string `json:"vendor_name" bson:"vendor_name"`
+
+	// Current is the vendor's inventory at the time of the request
+	Current Inventory `json:"current" bson:"current"`
+
+	// Requested is the entire inventory desired by the vendor
+	Requested Inventory `json:"requested" bson:"requested"`
+
+	// CurrentSpecs are the specifications of the vendor's items at the time of the request
+	CurrentSpecs EquipmentSpecs `json:"current_specs,omitempty" bson:"current_specs,omitempty"`
+
+	// RequestedSpecs are the entire specifications desired by the vendor
+	// Null when the request leaves the specifications untouched, hence it is not omitted when empty
+	RequestedSpecs EquipmentSpecs `json:"requested_specs" bson:"requested_specs"`
+
+	// Note from the vendor explaining the change
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+
+	// Status is either PENDING, APPROVED or REJECTED
+	Status string `json:"status" bson:"status"`
+
+	// Email ID of the admin who reviewed the request along with the reason for the decision
+	Reviewer     string `json:"-" bson:"reviewer,omitempty"`
+	ReviewReason string `json:"review_reason,omitempty" bson:"review_reason,omitempty"`
+
+	Created  int64 `json:"created" bson:"created"`
+	Reviewed int64 `json:"reviewed,omitempty" bson:"reviewed,omitempty"`
+}
+
+// Diff returns the items whose quantities differ between the given inventory and the requested one
+func (request *InventoryRequest) Diff(current Inventory) map[string]InventoryChange {
+	diff := make(map[string]InventoryChange)
+	for key := range current.Subtract(request.Requested) {
+		diff[key] = InventoryChange{
+			Current:   current[key],
+			Requested: request.Requested[key],
+		}
+	}
+	return diff
+}
+
+// SpecsDiff returns the items whose specifications differ between the given specifications and the requested ones
+func (request *InventoryRequest) SpecsDiff(current EquipmentSpecs) map[string]SpecsChange {
+	diff := make(map[string]SpecsChange)
+	if request.RequestedSpecs == nil {
+		return diff
+	}
+	for key := range current.Merge(request.RequestedSpecs) {
+		if current[key] != request.RequestedSpecs[key] {
+			diff[key] = SpecsChange{
+				Current:   current[key],
+				Requested: request.RequestedSpecs[key],
+			}
+		}
+	}
+	return diff
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Defaults for vendor matching when it isn't configured
+const (
+	// DefaultMatchingRadiusKm is the distance within which vendors are invited to a post
+	DefaultMatchingRadiusKm = 100
+
+	// DefaultMaxInvitations is the maximum number of vendors invited to a post
+	DefaultMaxInvitations = 20
+)
+
+// Invitation is sent to a vendor whose fleet matches the requirements of a newly created post
+type Invitation struct {
+	ID     primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+
+	// Email ID and name of the invited vendor
+	Vendor     string `json:"vendor" bson:"vendor"`
+	VendorName string `json:"vendor_name" bson:"vendor_name"`
+
+	// Matched is the part of the post's requirements which the vendor's free inventory can supply
+	Matched Inventory `json:"matched" bson:"matched"`
+
+	// DistanceKm is the distance between the base of the vendor's fleet and the post's location
+	DistanceKm float64 `json:"distance_km" bson:"distance_km"`
+
+	// Rank of the vendor among all the vendors invited to the post, starting from 1
+	Rank int `json:"rank" bson:"rank"`
+
+	// Emailed denotes whether the invitation was sent over email as well
+	Emailed bool `json:"emailed" bson:"emailed"`
+
+	Created int64 `json:"created" bson:"created"`
+}
+
+// VendorMatch is a vendor near a post whose inventory holds atleast one of the required items
+type VendorMatch struct {
+	Email    string `bson:"email"`
+	Username string `bson:"username"`
+
+	// Inventory is the part of the vendor's inventory which is free throughout the post's window
+	Inventory Inventory `bson:"inventory"`
+
+	Rating           *RatingSummary `bson:"rating"`
+	InvitationEmails bool           `bson:"invitation_emails"`
+	DistanceKm       float64        `bson:"distance_km"`
+}
+
+// RankVendorMatches returns the invitations for the best matching vendors, atmost limit of them
+// Vendors are ranked by how much of the requirements their free inventory can supply, then by their distance and then by their rating
+// Vendors who can't supply any of the requirements are left out
+func RankVendorMatches(requirements Inventory, matches []VendorMatch, limit int) []Invitation {
+	type rankedMatch struct {
+		match   VendorMatch
+		matched Inventory
+		units   int64
+	}
+	ranked := make([]rankedMatch, 0, len(matches))
+	for _, match := range matches {
+		matched := make(Inventory)
+		for key, required := range requirements {
+			if supply := match.Inventory[key]; supply > 0 && required > 0 {
+				if supply > required {
+					supply = required
+				}
+				matched[key] = supply
+			}
+		}
+		if matched.IsEmpty() {
+			continue
+		}
+		ranked = append(ranked, rankedMatch{
+			match:   match,
+			matched: matched,
+			units:   totalUnits(matched),
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].units != ranked[j].units {
+			return ranked[i].units > ranked[j].units
+		}
+		if ranked[i].match.DistanceKm != ranked[j].match.DistanceKm {
+			return ranked[i].match.DistanceKm < ranked[j].match.DistanceKm
+		}
+		var iRating, jRating float64
+		if ranked[i].match.Rating != nil {
+			iRating = ranked[i].match.Rating.Overall()
+		}
+		if ranked[j].match.Rating != nil {
+			jRating = ranked[j].match.Rating.Overall()
+		}
+		return iRating > jRating
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	invitations := make([]Invitation, 0, len(ranked))
+	for idx, item := range ranked {
+		invitations = append(invitations, Invitation{
+			Vendor:     item.match.Email,
+			VendorName: item.match.Username,
+			Matched:    item.matched,
+			DistanceKm: item.match.DistanceKm,
+			Rank:       idx + 1,
+			Emailed:    item.match.InvitationEmails,
+		})
+	}
+	return invitations
+}
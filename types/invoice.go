@@ -0,0 +1,231 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// secondsPerDay is used for converting the active duration of a post into billable days
+const secondsPerDay = 24 * 3600
+
+// Currency is the currency in which all rates and invoices are denominated
+const Currency = "INR"
+
+// InvoiceLineItem stores the charges of a single equipment line of a vendor whose offer was accepted
+type InvoiceLineItem struct {
+	// Name of the vendor
+	Vendor string `json:"vendor" bson:"vendor"`
+
+	// Encrypted email ID of the vendor, same as the key of its accepted offer
+	VendorKey string `json:"-" bson:"vendor_key"`
+
+	// Equipment billed in the line in the form of <equipment key>:<quantity>
+	// Items of legacy offers which are priced as a whole are billed together in a single line
+	Equipment Inventory `json:"equipment" bson:"equipment"`
+
+	// Price quoted by the vendor for the line along with the number of hours, days or trips billed, 1 for lump sums
+	Price Price `json:"price" bson:"price"`
+	Units int64 `json:"units" bson:"units"`
+
+	// RatePerDay is the fee per day for all of the vendor's equipment, only held by invoices issued before per item pricing
+	RatePerDay float64 `json:"rate_per_day,omitempty" bson:"rate_per_day,omitempty"`
+
+	// Number of days the vendor's equipment was engaged i.e the post was ONGOING after the vendor's offer was accepted
+	BillableDays int64   `json:"billable_days" bson:"billable_days"`
+	Amount       float64 `json:"amount" bson:"amount"`
+}
+
+// Invoice stores the bill generated for a client when a post is completed
+type Invoice struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Number is the human readable sequential identifier of the invoice, Ex:- EZF-000042
+	Number   string `json:"number" bson:"number"`
+	Sequence int64  `json:"-" bson:"sequence"`
+
+	PostID   primitive.ObjectID `json:"post_id" bson:"post_id"`
+	PostName string             `json:"post_name" bson:"post_name"`
+
+	// Email ID and name of the client who owns the post
+	Client     string `json:"-" bson:"client"`
+	ClientName string `json:"client_name" bson:"client_name"`
+
+	LineItems []InvoiceLineItem `json:"line_items" bson:"line_items"`
+
+	// Number of days the post was ONGOING excluding the time it was paused, partial days are billed as whole days
+	BillableDays int64 `json:"billable_days" bson:"billable_days"`
+
+	Subtotal           float64 `json:"subtotal" bson:"subtotal"`
+	PlatformFeePercent float64 `json:"platform_fee_percent" bson:"platform_fee_percent"`
+	PlatformFee        float64 `json:"platform_fee" bson:"platform_fee"`
+	GSTPercent         float64 `json:"gst_percent" bson:"gst_percent"`
+	GST                float64 `json:"gst" bson:"gst"`
+	Total              float64 `json:"total" bson:"total"`
+	Currency           string  `json:"currency" bson:"currency"`
+
+	// Paid is the amount in paise paid by the client against the invoice so far
+	Paid int64 `json:"-" bson:"paid"`
+
+	// Issued is the timestamp at which the post was marked as completed
+	Issued int64 `json:"issued" bson:"issued"`
+}
+
+// roundCurrency rounds off an amount to the nearest paisa
+func roundCurrency(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// billableDays converts a duration in seconds into days, partial days are billed as whole days
+func billableDays(duration int64) int64 {
+	return int64(math.Ceil(float64(duration) / secondsPerDay))
+}
+
+// NewInvoice creates the invoice of a post which is being completed at the given timestamp
+// Every vendor is charged only for the work periods after its offer was accepted
+// The platform fee is charged on the subtotal and GST is levied on the subtotal as well as the platform fee
+// The invoice number is assigned when the invoice is persisted
+func NewInvoice(post *Post, completed int64, platformFeePercent, gstPercent float64) *Invoice {
+	invoice := &Invoice{
+		PostID:             post.ID,
+		PostName:           post.Name,
+		Client:             post.Owner,
+		ClientName:         post.OwnerName,
+		LineItems:          make([]InvoiceLineItem, 0, len(post.AcceptedOffers)),
+		BillableDays:       billableDays(post.EngagedDuration(0, completed)),
+		PlatformFeePercent: platformFeePercent,
+		GSTPercent:         gstPercent,
+		Currency:           Currency,
+		Issued:             completed,
+	}
+
+	for key, offer := range post.AcceptedOffers {
+		duration := post.EngagedDuration(offer.Accepted, completed)
+		for _, item := range offerLineItems(offer, duration) {
+			item.VendorKey = key
+			invoice.LineItems = append(invoice.LineItems, item)
+			invoice.Subtotal += item.Amount
+		}
+	}
+	sort.SliceStable(invoice.LineItems, func(i, j int) bool {
+		if invoice.LineItems[i].Vendor != invoice.LineItems[j].Vendor {
+			return invoice.LineItems[i].Vendor < invoice.LineItems[j].Vendor
+		}
+		return lineKey(invoice.LineItems[i]) < lineKey(invoice.LineItems[j])
+	})
+
+	invoice.Subtotal = roundCurrency(invoice.Subtotal)
+	invoice.PlatformFee = roundCurrency(invoice.Subtotal * platformFeePercent / 100)
+	invoice.GST = roundCurrency((invoice.Subtotal + invoice.PlatformFee) * gstPercent / 100)
+	invoice.Total = roundCurrency(invoice.Subtotal + invoice.PlatformFee + invoice.GST)
+	return invoice
+}
+
+// offerLineItems returns the line items of an accepted offer whose equipment was engaged for the given seconds
+// Every priced item is billed in its own line, the remaining items of legacy offers are billed together at the offer's rate per day
+func offerLineItems(offer Offer, duration int64) []InvoiceLineItem {
+	days := billableDays(duration)
+	items := make([]InvoiceLineItem, 0, len(offer.Content))
+	unpriced := make(Inventory)
+	for key, quantity := range offer.Content {
+		if quantity == 0 {
+			continue
+		}
+		price, ok := offer.Prices[key]
+		if !ok {
+			unpriced[key] = quantity
+			continue
+		}
+		units, amount := price.Charge(quantity, duration)
+		items = append(items, InvoiceLineItem{
+			Vendor:       offer.Name,
+			Equipment:    Inventory{key: quantity},
+			Price:        price,
+			Units:        units,
+			BillableDays: days,
+			Amount:       amount,
+		})
+	}
+	if len(unpriced) > 0 {
+		items = append(items, InvoiceLineItem{
+			Vendor:    offer.Name,
+			Equipment: unpriced,
+			Price: Price{
+				Amount: offer.Rate,
+				Unit:   PerDay,
+			},
+			Units:        days,
+			BillableDays: days,
+			Amount:       roundCurrency(offer.Rate * float64(days)),
+		})
+	}
+	return items
+}
+
+// lineKey returns the smallest equipment key of a line item, used for ordering the line items of a vendor
+func lineKey(item InvoiceLineItem) string {
+	smallest := EMPTY
+	for key := range item.Equipment {
+		if smallest == EMPTY || key < smallest {
+			smallest = key
+		}
+	}
+	return smallest
+}
+
+// Due returns the total amount of the invoice in paise
+// It is computed from the individual components so that it always matches the invoice's journal
+func (invoice *Invoice) Due() int64 {
+	due := ToPaise(invoice.PlatformFee) + ToPaise(invoice.GST)
+	for _, item := range invoice.LineItems {
+		due += ToPaise(item.Amount)
+	}
+	return due
+}
+
+// Outstanding returns the amount in paise which is yet to be paid by the client
+func (invoice *Invoice) Outstanding() int64 {
+	return invoice.Due() - invoice.Paid
+}
+
+// Journal returns the ledger journal recording the issue of the invoice
+// The client owes the total amount while the vendors are owed their line items and the platform keeps the fee and the GST
+// vendorEmails holds the email IDs of the vendors in the form of <vendor key>:<email ID>
+// The line items of vendors missing from it are credited to the unallocated account
+func (invoice *Invoice) Journal(vendorEmails map[string]string) *Journal {
+	journal := &Journal{
+		Kind:     INVOICE,
+		Invoice:  invoice.Number,
+		Postings: make([]Posting, 0, len(invoice.LineItems)+3),
+		Created:  invoice.Issued,
+	}
+	journal.Debit(ClientAccount(invoice.Client), invoice.Due())
+
+	// A vendor is credited once for all of its line items
+	vendorKeys := make([]string, 0)
+	owed := make(map[string]int64)
+	for _, item := range invoice.LineItems {
+		if _, ok := owed[item.VendorKey]; !ok {
+			vendorKeys = append(vendorKeys, item.VendorKey)
+		}
+		owed[item.VendorKey] += ToPaise(item.Amount)
+	}
+	for _, vendorKey := range vendorKeys {
+		if vendorEmail, ok := vendorEmails[vendorKey]; ok {
+			journal.Credit(VendorAccount(vendorEmail), owed[vendorKey])
+		} else {
+			journal.Credit(UnallocatedAccount, owed[vendorKey])
+		}
+	}
+	journal.Credit(CommissionAccount, ToPaise(invoice.PlatformFee))
+	journal.Credit(GSTAccount, ToPaise(invoice.GST))
+	return journal
+}
+
+// SetSequence sets the sequence of the invoice along with its number
+func (invoice *Invoice) SetSequence(prefix string, sequence int64) {
+	invoice.Sequence = sequence
+	invoice.Number = fmt.Sprintf("%s-%06d", prefix, sequence)
+}
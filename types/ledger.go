@@ -0,0 +1,132 @@
+package types
+
+import (
+	"errors"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of journals recorded in the payments ledger
+const (
+	// INVOICE journals record the amounts owed by a client and to the vendors when an invoice is issued
+	INVOICE = "INVOICE"
+
+	// PAYMENT journals record the money received from a client against an invoice
+	PAYMENT = "PAYMENT"
+
+	// PAYOUT journals record the money paid to a vendor
+	PAYOUT = "PAYOUT"
+
+	// REVERSAL journals reverse a payout which was rejected by the payment gateway
+	REVERSAL = "REVERSAL"
+)
+
+// Accounts of the platform in the payments ledger
+const (
+	// CashAccount holds the money received from clients which has not been paid out yet
+	CashAccount = "platform:cash"
+
+	// CommissionAccount holds the platform fees charged on invoices
+	CommissionAccount = "platform:commission"
+
+	// GSTAccount holds the GST collected on invoices which is to be remitted
+	GSTAccount = "platform:gst"
+
+	// UnallocatedAccount holds the amounts owed for the line items of invoices whose vendor couldn't be identified
+	// They need to be settled manually
+	UnallocatedAccount = "platform:unallocated"
+)
+
+// ClientAccount returns the receivable account of a client i.e the money the client owes us
+func ClientAccount(email string) string {
+	return "client:" + email
+}
+
+// VendorAccount returns the payable account of a vendor i.e the money we owe the vendor
+func VendorAccount(email string) string {
+	return "vendor:" + email
+}
+
+// ErrUnbalancedJournal is returned when the debits of a journal do not match its credits
+var ErrUnbalancedJournal = errors.New("Total debits of the journal do not match its total credits")
+
+// ToPaise converts an amount in rupees into paise
+// All amounts in the ledger are stored in paise so that balances are not subject to floating point errors
+func ToPaise(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// ToRupees converts an amount in paise into rupees
+func ToRupees(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+// Posting is a single debit or credit to an account, amounts are in paise
+type Posting struct {
+	Account string `json:"account" bson:"account"`
+	Debit   int64  `json:"debit" bson:"debit"`
+	Credit  int64  `json:"credit" bson:"credit"`
+}
+
+// Journal is a single transaction of the double-entry payments ledger
+// Its postings are stored within the same document so that a journal is always written atomically
+type Journal struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Kind is either INVOICE, PAYMENT, PAYOUT or REVERSAL
+	Kind string `json:"kind" bson:"kind"`
+
+	// Number of the invoice the journal belongs to, empty for payouts
+	Invoice string `json:"invoice,omitempty" bson:"invoice,omitempty"`
+
+	// Reference is the ID of the transaction with the payment gateway
+	Reference string `json:"reference,omitempty" bson:"reference,omitempty"`
+
+	Postings []Posting `json:"postings" bson:"postings"`
+	Created  int64     `json:"created" bson:"created"`
+}
+
+// Debit adds a debit posting to the journal, zero amounts are skipped
+func (journal *Journal) Debit(account string, amount int64) *Journal {
+	if amount != 0 {
+		journal.Postings = append(journal.Postings, Posting{Account: account, Debit: amount})
+	}
+	return journal
+}
+
+// Credit adds a credit posting to the journal, zero amounts are skipped
+func (journal *Journal) Credit(account string, amount int64) *Journal {
+	if amount != 0 {
+		journal.Postings = append(journal.Postings, Posting{Account: account, Credit: amount})
+	}
+	return journal
+}
+
+// Validate checks whether the journal is balanced and holds only non-negative amounts
+func (journal *Journal) Validate() error {
+	balance := int64(0)
+	for _, posting := range journal.Postings {
+		if posting.Debit < 0 || posting.Credit < 0 {
+			return errors.New("Postings cannot hold negative amounts")
+		}
+		balance += posting.Debit - posting.Credit
+	}
+	if balance != 0 {
+		return ErrUnbalancedJournal
+	}
+	return nil
+}
+
+// AccountBalance stores the totals of an account in the ledger, amounts are in paise
+type AccountBalance struct {
+	Account string `json:"account" bson:"_id"`
+	Debit   int64  `json:"debit" bson:"debit"`
+	Credit  int64  `json:"credit" bson:"credit"`
+}
+
+// Balance returns the debits minus the credits of the account
+// Positive for receivables such as client accounts and negative for payables such as vendor accounts
+func (balance AccountBalance) Balance() int64 {
+	return balance.Debit - balance.Credit
+}
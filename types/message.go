@@ -0,0 +1,49 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Message is a single message in the negotiation thread between a client and a vendor over the vendor's offer on a post
+type Message struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+
+	// OfferKey identifies the vendor's side of the thread i.e the encrypted email ID of the vendor
+	// The vendor's email ID is never revealed to the client or to other vendors
+	OfferKey string `json:"offer_key" bson:"offer_key"`
+	Vendor   string `json:"-" bson:"vendor"`
+
+	// Email ID, role and name of the user who sent the message
+	Sender     string `json:"-" bson:"sender"`
+	SenderRole string `json:"sender_role" bson:"sender_role"`
+	SenderName string `json:"sender_name" bson:"sender_name"`
+
+	Text string `json:"text,omitempty" bson:"text,omitempty"`
+
+	// Quantities and prices of the items proposed in the message, if any
+	Quantities Inventory `json:"quantities,omitempty" bson:"quantities,omitempty"`
+	Prices     Prices    `json:"prices,omitempty" bson:"prices,omitempty"`
+
+	// Read is the timestamp at which the recipient read the message, 0 for unread messages
+	Read int64 `json:"read,omitempty" bson:"read,omitempty"`
+
+	Created int64 `json:"created" bson:"created"`
+}
+
+// Validate checks if a message holds either some text or a proposal
+func (message *Message) Validate() error {
+	if message.Text == EMPTY && message.Quantities.IsEmpty() && len(message.Prices) == 0 {
+		return errors.New("Message should hold either a text or the proposed quantities or prices")
+	}
+	for key, price := range message.Prices {
+		if err := price.Validate(); err != nil {
+			return fmt.Errorf("Invalid price for item %s: %s", key, err.Error())
+		}
+	}
+	return nil
+}
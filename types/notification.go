@@ -6,6 +6,12 @@ const (
 	// RequestOfferChange is the type of notification sent when a client requests a vendor to change his offer
 	RequestOfferChange = "REQUEST_OFFER_CHANGE"
 
+	// ACCEPTED denotes a change request which has been applied to the vendor's offer
+	ACCEPTED = "ACCEPTED"
+
+	// DECLINED denotes a change request which has been turned down by the vendor
+	DECLINED = "DECLINED"
+
 	// INFO is the type of notification sent as means of informing clients/vendors for cases such as when an offer has been accepted, a post has started/completed etc
 	INFO = "INFO"
 )
@@ -18,7 +24,8 @@ type Notification struct {
 	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 
 	// PostID is the ID of the post document in mongoDB with which the notification is concerned
-	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+	// Notifications which aren't concerned with any post such as the review of inventory change requests don't hold it
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id,omitempty"`
 
 	// Recipent is the email address of the recipent of the notification
 	Recipent string `json:"recipent" bson:"recipent"`
@@ -35,8 +42,26 @@ type Notification struct {
 	// This field is only populated if the notification is of type REQUEST_OFFER_CHANGE
 	DesiredContent *Inventory `json:"desired_content,omitempty" bson:"desired_content,omitempty"`
 
+	// Resolution is either ACCEPTED or DECLINED once the vendor has responded to a REQUEST_OFFER_CHANGE notification
+	// A resolved change request cannot be responded to again
+	Resolution string `json:"resolution,omitempty" bson:"resolution,omitempty"`
+
+	// Reason given by the vendor for declining a change request
+	Reason string `json:"reason,omitempty" bson:"reason,omitempty"`
+
 	// Read denotes whether the notification is read or not
 	Read bool `json:"read" bson:"read"`
 
 	Created int64 `json:"created" bson:"created"`
 }
+
+// OfferChangeAcceptance is the optional body of a vendor's request for accepting a change requested by a client
+// It holds the prices of the items being re-priced or newly offered as per the change
+type OfferChangeAcceptance struct {
+	Prices Prices `json:"prices"`
+}
+
+// OfferChangeDecline is the body of a vendor's request for declining a change requested by a client
+type OfferChangeDecline struct {
+	Reason string `json:"reason" valid:"required"`
+}
@@ -1,6 +1,10 @@
 package types
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -14,6 +18,10 @@ const (
 	// ONGOING denotes the status when a job request is in progress
 	ONGOING = "ONGOING"
 
+	// COMPLETION_REQUESTED denotes the status when the client has marked a job request as complete
+	// and it awaits the confirmation of the vendors and the approval of an admin
+	COMPLETION_REQUESTED = "COMPLETION_REQUESTED"
+
 	// COMPLETED denotes the status when a job request is successfully completed
 	COMPLETED = "COMPLETED"
 
@@ -21,7 +29,50 @@ const (
 	DELETED = "DELETED"
 )
 
-// Location denotes the location of the job request
+// postTransitions holds the lifecycle of a post in the form of <current status>:<statuses it can move to>
+// COMPLETED and DELETED are terminal states, no transitions can be made out of them
+// A post is COMPLETED only once an admin approves the completion requested by the client, it goes back to ONGOING otherwise
+var postTransitions = map[string][]string{
+	OPEN:                 {ONGOING, DELETED},
+	ONGOING:              {OPEN, COMPLETION_REQUESTED, DELETED},
+	COMPLETION_REQUESTED: {COMPLETED, ONGOING},
+}
+
+// IsValidTransition checks whether a post can move from one status to another
+func IsValidTransition(from, to string) bool {
+	for _, status := range postTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransition stores a single change in the status of a post
+type StatusTransition struct {
+	// Email ID of the user who changed the status
+	Actor     string `json:"actor" bson:"actor"`
+	From      string `json:"from" bson:"from"`
+	To        string `json:"to" bson:"to"`
+	Timestamp int64  `json:"timestamp" bson:"timestamp"`
+}
+
+// CompletionResponse stores a vendor's response to the completion requested by the client
+type CompletionResponse struct {
+	// Confirmed is false if the vendor disputes the completion
+	Confirmed bool   `json:"confirmed" bson:"confirmed"`
+	Reason    string `json:"reason,omitempty" bson:"reason,omitempty"`
+	Timestamp int64  `json:"timestamp" bson:"timestamp"`
+}
+
+// WorkPeriod stores a single span of time in which the post was ONGOING
+type WorkPeriod struct {
+	Start int64 `json:"start" bson:"start"`
+	// Stop is 0 while the work period is in progress
+	Stop int64 `json:"stop,omitempty" bson:"stop"`
+}
+
+// Location denotes the location of the job request or the base of a vendor's fleet
 type Location struct {
 	// Always "Point"
 	Type       string `json:"-" bson:"type,omitempty"`
@@ -38,14 +89,129 @@ type Location struct {
 	Street      string    `json:"street_number" bson:"street_number,omitempty"`
 }
 
+// Initialize sets the GeoJSON point of the location from its latitude and longitude after checking their ranges
+func (location *Location) Initialize() error {
+	latitude, err := strconv.ParseFloat(location.Latitude, 64)
+	if err != nil {
+		return err
+	}
+	longitude, err := strconv.ParseFloat(location.Longtitude, 64)
+	if err != nil {
+		return err
+	}
+	// 2dsphere indexes reject the points which are out of range, NaN fails the comparisons as well
+	if !(math.Abs(latitude) <= 90 && math.Abs(longitude) <= 180) {
+		return errors.New("Latitude should be within [-90, 90] and longitude within [-180, 180]")
+	}
+	location.Coordinates = []float64{longitude, latitude}
+	location.Type = "Point"
+	return nil
+}
+
 // Offer stores the information of offers made by vendors to a post
 type Offer struct {
 	// Name of the vendor making the offer
 	Name    string    `json:"name" bson:"name"`
 	Created int64     `json:"created" bson:"created"`
 	Content Inventory `json:"content" bson:"content"`
-	// The fees charged for the vendor's services in indian rupees per day
-	Rate float64 `json:"rate" bson:"rate"`
+	// Timestamp at which the offer was first accepted, 0 for pending offers
+	Accepted int64 `json:"accepted,omitempty" bson:"accepted,omitempty"`
+	// Specifications of the offered items as declared in the vendor's inventory
+	Specs EquipmentSpecs `json:"specs,omitempty" bson:"specs,omitempty"`
+	// Prices of the offered items and the currency in which they are quoted
+	Prices   Prices `json:"prices,omitempty" bson:"prices,omitempty"`
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"`
+	// Rate is the legacy fee for the entire offer in indian rupees per day, offers priced per item don't hold it
+	Rate float64 `json:"rate,omitempty" bson:"rate,omitempty"`
+}
+
+// Split divides an offer into the part holding the given content and the remainder, if any
+// The prices and specifications of the offer are divided along with its content
+// Legacy offers priced as a whole at a rate cannot be divided
+func (offer Offer) Split(content Inventory) (Offer, *Offer, error) {
+	if content.IsEmpty() {
+		return offer, nil, errors.New("Accepted part of an offer should hold atleast one item")
+	}
+	for key, value := range content {
+		if value < 0 {
+			return offer, nil, fmt.Errorf("Quantity of %s cannot be negative", key)
+		}
+	}
+	if content.Exceeds(offer.Content) {
+		return offer, nil, errors.New("Accepted part exceeds the offer")
+	}
+
+	part := offer
+	part.Content = make(Inventory)
+	for key, value := range content {
+		if value != 0 {
+			part.Content[key] = value
+		}
+	}
+	part.Prices = offer.Prices.Subset(part.Content)
+	part.Specs = offer.Specs.Subset(part.Content)
+
+	remaining := offer.Content.Subtract(part.Content)
+	if remaining.IsEmpty() {
+		return part, nil, nil
+	}
+	// The rate of a legacy offer is quoted for the offer as a whole and can't be divided among its parts
+	if len(offer.Prices) == 0 {
+		return offer, nil, errors.New("Offer is priced as a whole and can only be accepted entirely")
+	}
+	remainder := offer
+	remainder.Content = remaining
+	remainder.Prices = offer.Prices.Subset(remaining)
+	remainder.Specs = offer.Specs.Subset(remaining)
+	return part, &remainder, nil
+}
+
+// CheckMerge checks if an offer can be merged into this accepted offer without re-pricing the units accepted earlier
+// Legacy offers priced as a whole can only be merged with offers of the same rate, per item prices need to match for the common items
+func (accepted Offer) CheckMerge(offer Offer) error {
+	if len(accepted.Prices) == 0 || len(offer.Prices) == 0 {
+		if len(accepted.Prices) != len(offer.Prices) || accepted.Rate != offer.Rate {
+			return errors.New("Offer is priced differently from the vendor's accepted offer")
+		}
+		return nil
+	}
+	for key, price := range offer.Prices {
+		if current, ok := accepted.Prices[key]; ok && current != price {
+			return fmt.Errorf("Price of %s differs from the vendor's accepted offer", key)
+		}
+	}
+	return nil
+}
+
+// OfferAcceptance is the optional body of a client's request for accepting an offer
+// Content holds the part of the offer being accepted, the entire offer is accepted if it is empty
+// The remainder of a partially accepted offer stays pending if KeepRemainder is set, else it is released
+type OfferAcceptance struct {
+	Content       Inventory `json:"content"`
+	KeepRemainder bool      `json:"keep_remainder"`
+}
+
+// OfferBundle is the body of a client's request for accepting several offers on a post at once
+// It holds the acceptances in the form of <offer key>:<acceptance>, an empty acceptance accepts the entire offer
+type OfferBundle struct {
+	Offers map[string]OfferAcceptance `json:"offers"`
+}
+
+// BundledOffer is a single offer being accepted as a part of a bundle
+type BundledOffer struct {
+	OfferKey    string
+	VendorEmail string
+
+	// Accepted part of the offer and the part which stays pending, if any
+	Accepted  Offer
+	Remainder *Offer
+}
+
+// OfferInput is the body of a vendor's request for making an offer
+type OfferInput struct {
+	Content  Inventory `json:"content"`
+	Prices   Prices    `json:"prices"`
+	Currency string    `json:"currency"`
 }
 
 // Post stores the information about a job request
@@ -64,6 +230,15 @@ type Post struct {
 	// This is dynamic and shall change as offers are accepted/rejected
 	Requirements Inventory `json:"requirements" bson:"requirements" valid:"required"`
 
+	// Specifications which the required items need to meet
+	// Ex:- A client needing a 500 tonne crane specifies {"Crane": {"capacity_tonnes": 500}}
+	RequirementSpecs EquipmentSpecs `json:"requirement_specs,omitempty" bson:"requirement_specs,omitempty"`
+
+	// StartDate and EndDate are the unix timestamps denoting the duration of the job
+	// The inventories of the vendors whose offers are accepted are reserved for this duration
+	StartDate int64 `json:"start_date" bson:"start_date" valid:"required"`
+	EndDate   int64 `json:"end_date" bson:"end_date" valid:"required"`
+
 	// Additional information accompanying the post's requirements
 	// Ex:- A client needs 500 tonnes crane so he can specify that within comments
 	Comments map[string]string `json:"comments" bson:"comments"`
@@ -75,10 +250,22 @@ type Post struct {
 	// When offers are accepted by the client, they are moved here
 	AcceptedOffers map[string]Offer `json:"accepted_offers,omitempty" bson:"accepted_offers,omitempty"`
 
-	// Status can be either OPEN, ONGOING, COMPLETED or DELETED
-	Status  string `json:"status" bson:"status"`
-	Created int64  `json:"created" bson:"created"`
-	Updated int64  `json:"-" bson:"updated"`
+	// Status can be either OPEN, ONGOING, COMPLETION_REQUESTED, COMPLETED or DELETED
+	Status string `json:"status" bson:"status"`
+
+	// In the form of <encrypted email ID of the vendor>:<the vendor's response to the completion requested by the client>
+	// Only populated while the post is COMPLETION_REQUESTED and is cleared whenever a new completion is requested
+	CompletionResponses map[string]CompletionResponse `json:"completion_responses,omitempty" bson:"completion_responses,omitempty"`
+
+	// History holds all the status transitions of the post in chronological order
+	History []StatusTransition `json:"history,omitempty" bson:"history,omitempty"`
+
+	// WorkPeriods holds the spans of time in which the post was ONGOING in chronological order
+	// A new work period starts on activation and stops on deactivation, completion or deletion
+	WorkPeriods []WorkPeriod `json:"work_periods,omitempty" bson:"work_periods,omitempty"`
+
+	Created int64 `json:"created" bson:"created"`
+	Updated int64 `json:"-" bson:"updated"`
 }
 
 // Initialize initializes the post parameters during its creation
@@ -89,18 +276,12 @@ func (post *Post) Initialize() error {
 	// Default maps
 	post.Offers = make(map[string]Offer)
 	post.AcceptedOffers = make(map[string]Offer)
+	post.CompletionResponses = nil
 
 	// Location
-	latitude, err := strconv.ParseFloat(post.Location.Latitude, 64)
-	if err != nil {
-		return err
-	}
-	longitude, err := strconv.ParseFloat(post.Location.Longtitude, 64)
-	if err != nil {
+	if err := post.Location.Initialize(); err != nil {
 		return err
 	}
-	post.Location.Coordinates = []float64{longitude, latitude}
-	post.Location.Type = "Point"
 
 	// Timestamp
 	post.Created = time.Now().Unix()
@@ -112,6 +293,66 @@ func (post *Post) Initialize() error {
 	return nil
 }
 
+// Window returns the time window [start, end) for which the vendors' inventories are reserved
+// Posts created before the introduction of start and end dates are considered to be open-ended
+func (post *Post) Window() (int64, int64) {
+	if post.EndDate == 0 {
+		return post.StartDate, math.MaxInt64
+	}
+	return post.StartDate, post.EndDate
+}
+
+// Periods returns the work periods of the post
+// For posts activated before the introduction of work periods they are derived from the status history
+// and posts without any history are considered to be ONGOING since their last update
+func (post *Post) Periods() []WorkPeriod {
+	if len(post.WorkPeriods) > 0 {
+		return post.WorkPeriods
+	}
+	periods := make([]WorkPeriod, 0)
+	for _, transition := range post.History {
+		if transition.To == ONGOING {
+			periods = append(periods, WorkPeriod{Start: transition.Timestamp})
+		} else if transition.From == ONGOING && len(periods) > 0 {
+			periods[len(periods)-1].Stop = transition.Timestamp
+		}
+	}
+	if len(periods) == 0 && post.Status == ONGOING {
+		periods = append(periods, WorkPeriod{Start: post.Updated})
+	}
+	return periods
+}
+
+// EngagedDuration returns the number of seconds the post was ONGOING after the given timestamp till the given timestamp
+// Used for billing a vendor only for the work periods after its offer was accepted
+func (post *Post) EngagedDuration(since, till int64) int64 {
+	duration := int64(0)
+	for _, period := range post.Periods() {
+		start, stop := period.Start, period.Stop
+		if stop == 0 {
+			stop = till
+		}
+		if start < since {
+			start = since
+		}
+		if stop > start {
+			duration += stop - start
+		}
+	}
+	return duration
+}
+
+// Disputes returns the responses of the vendors who dispute the completion requested by the client
+func (post *Post) Disputes() map[string]CompletionResponse {
+	disputes := make(map[string]CompletionResponse)
+	for key, response := range post.CompletionResponses {
+		if !response.Confirmed {
+			disputes[key] = response
+		}
+	}
+	return disputes
+}
+
 // UpdateTimestamp updates the post's timestamp
 func (post *Post) UpdateTimestamp() {
 	post.Updated = time.Now().Unix()
@@ -133,23 +374,60 @@ type PostUpdate struct {
 	Location    *Location `json:"location,omitempty" bson:"location,omitempty"`
 	// Infrastructure required by the client
 	Requirements Inventory `json:"requirements,omitempty" bson:"requirements,omitempty"`
+	// Specifications which the required items need to meet
+	RequirementSpecs EquipmentSpecs `json:"requirement_specs,omitempty" bson:"requirement_specs,omitempty"`
+	// Duration of the job, can only be changed till no offers have been accepted
+	StartDate int64 `json:"start_date,omitempty" bson:"start_date,omitempty"`
+	EndDate   int64 `json:"end_date,omitempty" bson:"end_date,omitempty"`
 }
 
-// InitializeLocation initializes the post update location paramters
-func (postUpdate *PostUpdate) InitializeLocation() error {
-	// Location
-	latitude, err := strconv.ParseFloat(postUpdate.Location.Latitude, 64)
-	if err != nil {
-		return err
+// ChangesListing checks whether the update changes the requirements, the location or the dates of a post
+// compared to their previous values, vendors watching for matching posts aren't concerned with the rest of the fields
+func (postUpdate *PostUpdate) ChangesListing(previous *PostUpdate) bool {
+	if postUpdate.Requirements != nil && !postUpdate.Requirements.Equal(previous.Requirements) {
+		return true
 	}
-	longitude, err := strconv.ParseFloat(postUpdate.Location.Longtitude, 64)
-	if err != nil {
-		return err
+	if postUpdate.Location != nil && (previous.Location == nil || !reflect.DeepEqual(*postUpdate.Location, *previous.Location)) {
+		return true
 	}
-	postUpdate.Location.Coordinates = []float64{longitude, latitude}
-	postUpdate.Location.Type = "Point"
+	return (postUpdate.StartDate != 0 && postUpdate.StartDate != previous.StartDate) ||
+		(postUpdate.EndDate != 0 && postUpdate.EndDate != previous.EndDate)
+}
 
-	return nil
+// InitializeLocation initializes the post update location paramters
+func (postUpdate *PostUpdate) InitializeLocation() error {
+	return postUpdate.Location.Initialize()
+}
+
+// GeoQuery holds the parameters for searching posts around a point
+type GeoQuery struct {
+	Latitude  float64
+	Longitude float64
+	// Maximum distance of the posts from the point in kilometres
+	// Zero denotes no limit
+	RadiusKm float64
+}
+
+const (
+	// SortByUpdated sorts posts by their last updated timestamp, latest first
+	SortByUpdated = "updated"
+
+	// SortByDistance sorts posts by their distance from the point of the geo query, nearest first
+	SortByDistance = "distance"
+
+	// SortByClientRating sorts posts by the overall rating of their owner, highest first
+	SortByClientRating = "client_rating"
+)
+
+// PostQuery holds the optional parameters for searching open posts
+type PostQuery struct {
+	// Geo restricts the posts to a region, nil denotes no restriction
+	Geo *GeoQuery
+	// Minimum overall rating of the post's owner, zero denotes no limit
+	// Posts of clients who haven't been rated yet are skipped if a limit is set
+	MinClientRating float64
+	// Sort is either SortByUpdated, SortByDistance or SortByClientRating
+	Sort string
 }
 
 // PostStatus is a low memory footprint struct for retrieving the status of a post
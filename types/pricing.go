@@ -0,0 +1,117 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Units in which a vendor can price an equipment line of its offer
+const (
+	// PerHour prices are charged for every unit of the equipment for every hour it was engaged
+	PerHour = "PER_HOUR"
+
+	// PerDay prices are charged for every unit of the equipment for every day it was engaged
+	PerDay = "PER_DAY"
+
+	// PerTrip prices are charged for every unit of the equipment for the number of trips quoted
+	PerTrip = "PER_TRIP"
+
+	// LumpSum prices are charged once for the entire line irrespective of its quantity and duration
+	LumpSum = "LUMP_SUM"
+)
+
+// secondsPerHour is used for converting the engaged duration of an offer into billable hours
+const secondsPerHour = 3600
+
+// Price is the price quoted by a vendor for a single equipment line of its offer
+type Price struct {
+	Amount float64 `json:"amount" bson:"amount"`
+
+	// Unit is either PER_HOUR, PER_DAY, PER_TRIP or LUMP_SUM
+	Unit string `json:"unit" bson:"unit"`
+
+	// Trips is the number of trips quoted, only used for PER_TRIP prices
+	Trips int64 `json:"trips,omitempty" bson:"trips,omitempty"`
+}
+
+// Validate checks if the price holds a positive amount and a valid unit
+func (price Price) Validate() error {
+	if price.Amount <= 0 || math.IsInf(price.Amount, 0) || math.IsNaN(price.Amount) {
+		return errors.New("Price amount should be positive")
+	}
+	switch price.Unit {
+	case PerTrip:
+		if price.Trips < 1 {
+			return errors.New("Field 'trips' should be atleast 1 for PER_TRIP prices")
+		}
+	case PerHour, PerDay, LumpSum:
+		if price.Trips != 0 {
+			return errors.New("Field 'trips' is only allowed for PER_TRIP prices")
+		}
+	default:
+		return errors.New("Price unit should be either PER_HOUR, PER_DAY, PER_TRIP or LUMP_SUM")
+	}
+	return nil
+}
+
+// Charge returns the number of units billed and the amount charged for the given quantity of equipment engaged for the given seconds
+// Partial hours and days are billed as whole ones
+func (price Price) Charge(quantity, duration int64) (int64, float64) {
+	units := int64(1)
+	switch price.Unit {
+	case PerHour:
+		units = int64(math.Ceil(float64(duration) / secondsPerHour))
+	case PerDay:
+		units = billableDays(duration)
+	case PerTrip:
+		units = price.Trips
+	case LumpSum:
+		return units, roundCurrency(price.Amount)
+	}
+	return units, roundCurrency(price.Amount * float64(quantity*units))
+}
+
+// Prices holds the prices of the equipment lines of an offer in the form of <equipment key>:<price>
+type Prices map[string]Price
+
+// Validate checks if every offered item has a valid price and that no price is quoted for items which are not offered
+func (prices Prices) Validate(content Inventory) error {
+	for key, quantity := range content {
+		if quantity == 0 {
+			continue
+		}
+		price, ok := prices[key]
+		if !ok {
+			return fmt.Errorf("Price for item %s is missing", key)
+		}
+		if err := price.Validate(); err != nil {
+			return fmt.Errorf("Invalid price for item %s: %s", key, err.Error())
+		}
+	}
+	for key := range prices {
+		if content[key] == 0 {
+			return fmt.Errorf("Price quoted for item %s which is not offered", key)
+		}
+	}
+	return nil
+}
+
+// Subset returns the prices of the given items
+func (prices Prices) Subset(content Inventory) Prices {
+	subset := make(Prices)
+	for key, quantity := range content {
+		if price, ok := prices[key]; ok && quantity != 0 {
+			subset[key] = price
+		}
+	}
+	return subset
+}
+
+// ValidateCurrency checks if the currency of an offer is supported, an empty currency defaults to INR
+func ValidateCurrency(currency string) error {
+	if currency != EMPTY && currency != Currency {
+		return fmt.Errorf("Currency should be %s, no other currencies are supported yet", Currency)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RatingScores holds the scores given in a rating, each score lies between 1 and 5
+// When vendors rate clients, equipment condition denotes the condition in which the client returned the equipment
+type RatingScores struct {
+	Punctuality        int64 `json:"punctuality" bson:"punctuality" valid:"range(1|5)~Field 'punctuality' should be between 1 and 5"`
+	EquipmentCondition int64 `json:"equipment_condition" bson:"equipment_condition" valid:"range(1|5)~Field 'equipment_condition' should be between 1 and 5"`
+	Communication      int64 `json:"communication" bson:"communication" valid:"range(1|5)~Field 'communication' should be between 1 and 5"`
+}
+
+// Rating is the review given by a client to a vendor or by a vendor to a client after a post is COMPLETED
+type Rating struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+
+	// Email IDs of the user giving the rating and the user being rated
+	Rater string `json:"-" bson:"rater"`
+	Ratee string `json:"-" bson:"ratee"`
+
+	RaterName string `json:"rater_name" bson:"rater_name"`
+
+	// RateeRole is the role of the user being rated i.e either client or vendor
+	RateeRole string `json:"ratee_role" bson:"ratee_role"`
+
+	Scores  RatingScores `json:"scores" bson:"scores"`
+	Comment string       `json:"comment,omitempty" bson:"comment,omitempty" valid:"length(0|1000)~Field 'comment' should not exceed 1000 characters"`
+
+	Created int64 `json:"created" bson:"created"`
+}
+
+// RatingSummary holds the aggregate of all ratings received by a user
+// The totals of the scores are stored so that a new rating can be added atomically, the averages are derived from them
+type RatingSummary struct {
+	Count              int64 `bson:"count"`
+	Punctuality        int64 `bson:"punctuality"`
+	EquipmentCondition int64 `bson:"equipment_condition"`
+	Communication      int64 `bson:"communication"`
+}
+
+// average returns the average of a total rounded off to 2 decimal places
+func (summary *RatingSummary) average(total int64) float64 {
+	if summary.Count == 0 {
+		return 0
+	}
+	return math.Round(float64(total)/float64(summary.Count)*100) / 100
+}
+
+// Overall returns the average of all the scores
+func (summary *RatingSummary) Overall() float64 {
+	if summary.Count == 0 {
+		return 0
+	}
+	total := summary.Punctuality + summary.EquipmentCondition + summary.Communication
+	return math.Round(float64(total)/float64(3*summary.Count)*100) / 100
+}
+
+// MarshalJSON returns the averages of the scores instead of their totals
+func (summary *RatingSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(M{
+		"count":               summary.Count,
+		"punctuality":         summary.average(summary.Punctuality),
+		"equipment_condition": summary.average(summary.EquipmentCondition),
+		"communication":       summary.average(summary.Communication),
+		"overall":             summary.Overall(),
+	})
+}
@@ -0,0 +1,218 @@
+package types
+
+import (
+	"math"
+	"sort"
+)
+
+// maxIndivisibleLines is the maximum number of indivisible lines whose combinations are explored exhaustively
+// Only the cheapest ones per unit are considered beyond it
+const maxIndivisibleLines = 12
+
+// Recommendation is a combination of pending offers which covers the remaining requirements of a post
+type Recommendation struct {
+	// Offers to accept in the form of <offer key>:<acceptance>, usable as the body of the bundle acceptance
+	Offers map[string]OfferAcceptance `json:"offers"`
+
+	// Covered is the part of the remaining requirements covered by the offers and Short is the part which isn't
+	Covered Inventory `json:"covered"`
+	Short   Inventory `json:"short"`
+
+	// Cost is the estimated cost of the offers for the scheduled duration of the post
+	// Open-ended posts are estimated for a single day of engagement as their duration isn't known yet
+	Cost     float64 `json:"cost"`
+	Currency string  `json:"currency"`
+
+	// OpenEnded denotes whether the cost was estimated for a single day as the post has no end date
+	OpenEnded bool `json:"open_ended,omitempty"`
+
+	// Approximate denotes whether some indivisible lines were left out of the search, in which case a cheaper
+	// or more covering combination might exist
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// recommendationLine is a part of a pending offer which can be picked by the recommender
+// Divisible lines can be picked partially at a cost per unit whereas indivisible lines are picked entirely at a fixed cost
+type recommendationLine struct {
+	offerKey string
+	content  Inventory
+	cost     float64
+}
+
+// candidate is a combination of lines explored by the recommender
+type candidate struct {
+	picked  map[string]Inventory
+	covered int64
+	cost    float64
+}
+
+// betterThan checks if a candidate covers more than the other one, or covers the same at a lower cost
+func (c *candidate) betterThan(other *candidate) bool {
+	if other == nil || c.covered != other.covered {
+		return other == nil || c.covered > other.covered
+	}
+	return c.cost < other.cost
+}
+
+// recommendationLines splits the pending offers of a post into divisible and indivisible lines
+// Items priced per hour, day or trip are divisible while lump sums and legacy offers priced as a whole are indivisible
+// The param "free" holds the free inventory of the vendor behind every offer for the post's duration, offers missing from it are left out
+// Only the cheapest indivisible lines per unit are returned beyond maxIndivisibleLines, in which case truncated is true
+func (post *Post) recommendationLines(free map[string]Inventory) ([]recommendationLine, []recommendationLine, bool) {
+	// Open-ended posts are estimated for a single day of engagement
+	duration := int64(secondsPerDay)
+	if post.EndDate != 0 {
+		duration = post.EndDate - post.StartDate
+	}
+	divisible := make([]recommendationLine, 0)
+	indivisible := make([]recommendationLine, 0)
+	for offerKey, offer := range post.Offers {
+		available := free[offerKey]
+		if len(offer.Prices) == 0 {
+			if !offer.Content.IsEmpty() && !offer.Content.Exceeds(available) {
+				indivisible = append(indivisible, recommendationLine{
+					offerKey: offerKey,
+					content:  offer.Content,
+					cost:     offer.Rate * float64(billableDays(duration)),
+				})
+			}
+			continue
+		}
+		for key, quantity := range offer.Content {
+			price, ok := offer.Prices[key]
+			if !ok || quantity <= 0 {
+				continue
+			}
+			if price.Unit == LumpSum {
+				if quantity <= available[key] {
+					indivisible = append(indivisible, recommendationLine{
+						offerKey: offerKey,
+						content:  Inventory{key: quantity},
+						cost:     price.Amount,
+					})
+				}
+				continue
+			}
+			if capacity := int64(math.Min(float64(quantity), float64(available[key]))); capacity > 0 {
+				_, unitCost := price.Charge(1, duration)
+				divisible = append(divisible, recommendationLine{
+					offerKey: offerKey,
+					content:  Inventory{key: capacity},
+					cost:     unitCost,
+				})
+			}
+		}
+	}
+
+	sort.Slice(divisible, func(i, j int) bool {
+		if divisible[i].cost != divisible[j].cost {
+			return divisible[i].cost < divisible[j].cost
+		}
+		return divisible[i].offerKey < divisible[j].offerKey
+	})
+	sort.Slice(indivisible, func(i, j int) bool {
+		iUnits, jUnits := totalUnits(indivisible[i].content), totalUnits(indivisible[j].content)
+		return indivisible[i].cost*float64(jUnits) < indivisible[j].cost*float64(iUnits)
+	})
+	truncated := len(indivisible) > maxIndivisibleLines
+	if truncated {
+		indivisible = indivisible[:maxIndivisibleLines]
+	}
+	return divisible, indivisible, truncated
+}
+
+// totalUnits returns the total quantity of all items of an inventory
+func totalUnits(inventory Inventory) int64 {
+	total := int64(0)
+	for _, value := range inventory {
+		total += value
+	}
+	return total
+}
+
+// RecommendOffers picks the combination of pending offers which covers the most of the post's remaining requirements
+// at the lowest estimated cost within the given budget, an infinite budget yields the minimum cost combination
+// Every combination of indivisible lines is explored and the rest of the requirements are filled with the cheapest divisible units
+// The param "free" holds the free inventory of the vendor behind every offer for the post's duration
+func (post *Post) RecommendOffers(free map[string]Inventory, budget float64) *Recommendation {
+	divisible, indivisible, truncated := post.recommendationLines(free)
+
+	var best *candidate
+	for mask := 0; mask < 1<<len(indivisible); mask++ {
+		need := make(Inventory)
+		for key, value := range post.Requirements {
+			if value > 0 {
+				need[key] = value
+			}
+		}
+		current := &candidate{
+			picked: make(map[string]Inventory),
+		}
+		pick := func(line recommendationLine, content Inventory, cost float64) {
+			current.picked[line.offerKey] = current.picked[line.offerKey].Add(content)
+			current.cost += cost
+			for key, value := range content {
+				need[key] -= value
+				current.covered += value
+			}
+		}
+
+		// Indivisible lines can't be accepted beyond the requirements
+		feasible := true
+		for idx, line := range indivisible {
+			if mask&(1<<idx) == 0 {
+				continue
+			}
+			if line.content.Exceeds(need) || current.cost+line.cost > budget {
+				feasible = false
+				break
+			}
+			pick(line, line.content, line.cost)
+		}
+		if !feasible {
+			continue
+		}
+
+		// The cheapest units cover the most within the budget
+		for _, line := range divisible {
+			for key, capacity := range line.content {
+				units := int64(math.Min(float64(capacity), float64(need[key])))
+				if line.cost > 0 {
+					units = int64(math.Min(float64(units), math.Floor((budget-current.cost)/line.cost)))
+				}
+				if units > 0 {
+					pick(line, Inventory{key: units}, line.cost*float64(units))
+				}
+			}
+		}
+
+		if current.betterThan(best) {
+			best = current
+		}
+	}
+
+	recommendation := &Recommendation{
+		Offers:      make(map[string]OfferAcceptance),
+		Covered:     make(Inventory),
+		Currency:    Currency,
+		OpenEnded:   post.EndDate == 0,
+		Approximate: truncated,
+	}
+	if best != nil {
+		for offerKey, content := range best.picked {
+			recommendation.Offers[offerKey] = OfferAcceptance{
+				Content:       content,
+				KeepRemainder: true,
+			}
+			recommendation.Covered = recommendation.Covered.Add(content)
+		}
+		recommendation.Cost = roundCurrency(best.cost)
+	}
+	recommendation.Short = make(Inventory)
+	for key, value := range post.Requirements {
+		if short := value - recommendation.Covered[key]; short > 0 {
+			recommendation.Short[key] = short
+		}
+	}
+	return recommendation
+}
@@ -0,0 +1,56 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ReservationDrift stores the difference between the reservation expected from a post's accepted offer
+// and the reservation recorded in the ledger for a single vendor and post
+type ReservationDrift struct {
+	PostID primitive.ObjectID `json:"post_id"`
+
+	// Expected is derived from the vendor's accepted offer on the post
+	Expected Inventory `json:"expected"`
+
+	// Recorded is the reservation present in the ledger
+	Recorded Inventory `json:"recorded"`
+
+	// ExpectedWindow and RecordedWindow hold the [start, end) timestamps of the reservation
+	ExpectedWindow [2]int64 `json:"expected_window"`
+	RecordedWindow [2]int64 `json:"recorded_window"`
+}
+
+// InventoryReconciliation stores the reconciliation result of a single vendor's inventory
+type InventoryReconciliation struct {
+	// Email ID of the vendor
+	Vendor string `json:"vendor"`
+
+	// Inventory is the vendor's declared inventory
+	Inventory Inventory `json:"inventory"`
+
+	// ExpectedFree is the declared inventory minus the accepted offers on all posts which are yet to be completed or deleted
+	ExpectedFree Inventory `json:"expected_free"`
+
+	// RecordedFree is the declared inventory minus all reservations in the ledger
+	RecordedFree Inventory `json:"recorded_free"`
+
+	// Drifts holds the reservations which do not match the accepted offers, empty if the vendor is consistent
+	Drifts []ReservationDrift `json:"drifts"`
+}
+
+// UnreadableOffer denotes an accepted offer whose key could not be decrypted into the vendor's email
+// Possible cause: Encryption key or nonce was changed in config.toml mid-production
+type UnreadableOffer struct {
+	PostID   primitive.ObjectID `json:"post_id"`
+	OfferKey string             `json:"offer_key"`
+}
+
+// ReconciliationReport stores the result of reconciling all vendor inventories against the accepted offers
+type ReconciliationReport struct {
+	Vendors          []InventoryReconciliation `json:"vendors"`
+	UnreadableOffers []UnreadableOffer         `json:"unreadable_offers"`
+	// Drifted is the number of vendors whose reservations do not match the accepted offers
+	Drifted int `json:"drifted"`
+	// MissingVendors holds the vendors with accepted offers or reservations who no longer exist, they are never corrected
+	MissingVendors []string `json:"missing_vendors"`
+	// Applied denotes whether the ledger was corrected as per the report
+	Applied bool `json:"applied"`
+}
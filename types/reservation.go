@@ -0,0 +1,53 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Reservation stores the part of a vendor's inventory which is booked for a post within its time window
+// A vendor's free inventory for a time window is its declared inventory minus the reservations overlapping that window
+type Reservation struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Email ID of the vendor whose inventory is reserved
+	Vendor string `json:"-" bson:"vendor"`
+
+	// PostID is the ID of the post for which the inventory is reserved
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+
+	// Content holds the reserved inventory items
+	Content Inventory `json:"content" bson:"content"`
+
+	// Start and End are the unix timestamps denoting the reservation window [Start, End)
+	Start int64 `json:"start" bson:"start"`
+	End   int64 `json:"end" bson:"end"`
+
+	Created int64 `json:"created" bson:"created"`
+}
+
+// Overlaps checks whether the reservation overlaps with the time window [start, end)
+func (reservation *Reservation) Overlaps(start, end int64) bool {
+	return reservation.Start < end && start < reservation.End
+}
+
+// PeakReservedInventory returns the maximum amount of each inventory item reserved at any instant within the time window [start, end)
+// The reserved amount can only increase at the start of a reservation, hence it is sufficient to check
+// the start of the window and the start of every reservation within the window
+func PeakReservedInventory(reservations []Reservation, start, end int64) Inventory {
+	checkpoints := []int64{start}
+	for _, reservation := range reservations {
+		if reservation.Start > start && reservation.Start < end {
+			checkpoints = append(checkpoints, reservation.Start)
+		}
+	}
+
+	peak := Inventory{}
+	for _, instant := range checkpoints {
+		reserved := Inventory{}
+		for _, reservation := range reservations {
+			if reservation.Overlaps(instant, instant+1) {
+				reserved = reserved.Add(reservation.Content)
+			}
+		}
+		peak = peak.Max(reserved)
+	}
+	return peak
+}
@@ -0,0 +1,141 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// earthRadiusKm is used for computing the distance between a post and the centre of a search area
+const earthRadiusKm = 6371.0
+
+// SearchArea restricts a saved search to the posts within a radius around a point
+type SearchArea struct {
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+	RadiusKm  float64 `json:"radius_km" bson:"radius_km"`
+}
+
+// Contains checks if a GeoJSON point in the form of [longitude, latitude] lies within the area
+func (area *SearchArea) Contains(coordinates []float64) bool {
+	if len(coordinates) != 2 {
+		return false
+	}
+	toRadians := func(degrees float64) float64 {
+		return degrees * math.Pi / 180
+	}
+	lat1, lat2 := toRadians(area.Latitude), toRadians(coordinates[1])
+	deltaLat, deltaLng := lat2-lat1, toRadians(coordinates[0]-area.Longitude)
+	h := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+	return 2*earthRadiusKm*math.Asin(math.Min(1, math.Sqrt(h))) <= area.RadiusKm
+}
+
+// SavedSearch holds the criteria of a vendor for the open posts it wants to be alerted about
+type SavedSearch struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	// Email ID of the vendor who saved the search
+	Vendor string `json:"-" bson:"vendor"`
+
+	Name string `json:"name" bson:"name" valid:"required~Field 'name' is required but was not provided"`
+
+	// Items of which a matching post should require atleast one
+	Items []string `json:"items" bson:"items"`
+
+	// MinQuantity is the least quantity of one of the items which a matching post should require, zero denotes no limit
+	MinQuantity int64 `json:"min_quantity,omitempty" bson:"min_quantity,omitempty"`
+
+	// Region is matched against the state (administrative_area_level_1) of a post's location, empty denotes any region
+	Region string `json:"region,omitempty" bson:"region,omitempty"`
+
+	// Area restricts the search to a radius around a point, nil denotes no restriction
+	Area *SearchArea `json:"area,omitempty" bson:"area,omitempty"`
+
+	// DailyDigest denotes whether the matches are emailed to the vendor once a day besides the in-app notifications
+	DailyDigest bool `json:"daily_digest" bson:"daily_digest"`
+
+	Created int64 `json:"created" bson:"created"`
+	Updated int64 `json:"updated" bson:"updated"`
+}
+
+// Validate checks the criteria of the search, the items are validated against the catalog
+func (search *SavedSearch) Validate(catalog *Set) error {
+	if len(search.Items) == 0 {
+		return errors.New("Field 'items' should hold atleast one item")
+	}
+	seen := make(map[string]bool)
+	for _, item := range search.Items {
+		if !catalog.Contains(item) {
+			return fmt.Errorf("%s is an invalid lookup item", item)
+		}
+		if seen[item] {
+			return fmt.Errorf("Item %s is repeated", item)
+		}
+		seen[item] = true
+	}
+	if search.MinQuantity < 0 {
+		return errors.New("Field 'min_quantity' cannot be negative")
+	}
+	search.Region = strings.TrimSpace(search.Region)
+	if area := search.Area; area != nil {
+		if area.Latitude < -90 || area.Latitude > 90 || area.Longitude < -180 || area.Longitude > 180 {
+			return errors.New("Field 'area' should hold valid co-ordinates")
+		}
+		if area.RadiusKm <= 0 || math.IsInf(area.RadiusKm, 0) || math.IsNaN(area.RadiusKm) {
+			return errors.New("Field 'radius_km' of the area should be a positive number")
+		}
+	}
+	return nil
+}
+
+// Matches checks if an open post meets the criteria of the search
+func (search *SavedSearch) Matches(post *Post) bool {
+	if post.Status != OPEN {
+		return false
+	}
+	minQuantity := search.MinQuantity
+	if minQuantity < 1 {
+		minQuantity = 1
+	}
+	required := false
+	for _, item := range search.Items {
+		if post.Requirements[item] >= minQuantity {
+			required = true
+			break
+		}
+	}
+	if !required {
+		return false
+	}
+	if search.Region != EMPTY && !strings.EqualFold(search.Region, strings.TrimSpace(post.Location.AdminArea1)) {
+		return false
+	}
+	if search.Area != nil && !search.Area.Contains(post.Location.Coordinates) {
+		return false
+	}
+	return true
+}
+
+// SearchAlert records an open post which matched a vendor's saved search
+// An alert is recorded once per search and post, later updates of the post refresh it
+type SearchAlert struct {
+	SearchID   primitive.ObjectID `json:"search_id" bson:"search_id"`
+	SearchName string             `json:"search_name" bson:"search_name"`
+	Vendor     string             `json:"-" bson:"vendor"`
+	PostID     primitive.ObjectID `json:"post_id" bson:"post_id"`
+	PostName   string             `json:"post_name" bson:"post_name"`
+
+	// Digest denotes whether the alert awaits the vendor's daily email digest, it is unset once emailed
+	Digest bool `json:"-" bson:"digest"`
+
+	Updated int64 `json:"updated" bson:"updated"`
+}
+
+// SearchDigest holds the alerts awaiting a vendor's daily email digest
+type SearchDigest struct {
+	Vendor string        `bson:"_id"`
+	Alerts []SearchAlert `bson:"alerts"`
+}
@@ -29,3 +29,12 @@ func (s *Set) Contains(value string) bool {
 	_, c := s.m[value]
 	return c
 }
+
+// Values returns all elements present within the set
+func (s *Set) Values() []string {
+	values := make([]string, 0, len(s.m))
+	for value := range s.m {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	// SCHEDULED denotes a shipment which is yet to leave the vendor
+	SCHEDULED = "SCHEDULED"
+
+	// DISPATCHED denotes a shipment which has left the vendor
+	DISPATCHED = "DISPATCHED"
+
+	// IN_TRANSIT denotes a shipment which is on its way to the post's location
+	IN_TRANSIT = "IN_TRANSIT"
+
+	// DELIVERED denotes a shipment which has reached the post's location
+	DELIVERED = "DELIVERED"
+
+	// RETURNED denotes a shipment which has gone back to the vendor, either after the job or on a failed delivery
+	RETURNED = "RETURNED"
+
+	// CANCELLED denotes a shipment which was on its way when its offer was rejected
+	// It is set by the platform and is scheduled again if the vendor's offer is accepted and the post re-activated
+	CANCELLED = "CANCELLED"
+)
+
+// shipmentTransitions holds the lifecycle of a shipment in the form of <current status>:<statuses it can move to>
+// RETURNED is the terminal state, CANCELLED can't be moved to or from by the vendor
+var shipmentTransitions = map[string][]string{
+	SCHEDULED:  {DISPATCHED},
+	DISPATCHED: {IN_TRANSIT, DELIVERED, RETURNED},
+	IN_TRANSIT: {DELIVERED, RETURNED},
+	DELIVERED:  {RETURNED},
+}
+
+// IsValidShipmentTransition checks whether a shipment can move from one status to another
+func IsValidShipmentTransition(from, to string) bool {
+	for _, status := range shipmentTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProofOfDelivery stores the evidence recorded by a vendor on delivering a shipment
+type ProofOfDelivery struct {
+	// Name of the person at the post's location who received the shipment
+	ReceivedBy string `json:"received_by" bson:"received_by" valid:"required~Field 'received_by' is required but was not provided"`
+
+	// Link to a signed challan, photograph etc
+	DocumentURL string `json:"document_url,omitempty" bson:"document_url,omitempty" valid:"url~Field 'document_url' should be a valid URL"`
+
+	Note      string `json:"note,omitempty" bson:"note,omitempty"`
+	Timestamp int64  `json:"timestamp" bson:"timestamp"`
+}
+
+// ShipmentEvent stores a single change in the status of a shipment
+type ShipmentEvent struct {
+	// Email ID of the user who changed the status
+	Actor     string `json:"-" bson:"actor"`
+	Status    string `json:"status" bson:"status"`
+	Note      string `json:"note,omitempty" bson:"note,omitempty"`
+	Timestamp int64  `json:"timestamp" bson:"timestamp"`
+}
+
+// Shipment tracks the delivery of the equipment of a single accepted offer to the post's location
+type Shipment struct {
+	ID primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+
+	// OfferKey is the key of the accepted offer i.e the encrypted email ID of the vendor
+	OfferKey string `json:"offer_key" bson:"offer_key"`
+
+	// Email IDs of the vendor shipping the equipment and the client receiving it
+	Vendor string `json:"-" bson:"vendor"`
+	Client string `json:"-" bson:"client"`
+
+	VendorName string `json:"vendor_name" bson:"vendor_name"`
+
+	// Equipment being shipped in the form of <equipment key>:<quantity>
+	Content Inventory `json:"content" bson:"content"`
+
+	// Status is either SCHEDULED, DISPATCHED, IN_TRANSIT, DELIVERED, RETURNED or CANCELLED
+	Status string `json:"status" bson:"status"`
+
+	// ETA is the estimated timestamp of delivery as provided by the vendor
+	ETA int64 `json:"eta,omitempty" bson:"eta,omitempty"`
+
+	ProofOfDelivery *ProofOfDelivery `json:"proof_of_delivery,omitempty" bson:"proof_of_delivery,omitempty"`
+
+	// ReceiptConfirmed is the timestamp at which the client confirmed the receipt of the shipment, 0 if not confirmed
+	ReceiptConfirmed int64 `json:"receipt_confirmed,omitempty" bson:"receipt_confirmed,omitempty"`
+
+	// History holds all the status changes of the shipment in chronological order
+	History []ShipmentEvent `json:"history" bson:"history"`
+
+	Created int64 `json:"created" bson:"created"`
+	Updated int64 `json:"updated" bson:"updated"`
+}
+
+// ShipmentUpdate is the update of a shipment's status made by a vendor
+type ShipmentUpdate struct {
+	// Status to move the shipment to, the status is left untouched if empty
+	Status string `json:"status,omitempty"`
+
+	ETA  int64  `json:"eta,omitempty"`
+	Note string `json:"note,omitempty"`
+
+	// ProofOfDelivery is required for moving the shipment to DELIVERED
+	ProofOfDelivery *ProofOfDelivery `json:"proof_of_delivery,omitempty"`
+}
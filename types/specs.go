@@ -0,0 +1,129 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Specs stores the technical specifications of an equipment
+// In requirements, every numeric specification denotes the minimum acceptable value
+// and the fuel type denotes the exact fuel type desired by the client
+// Zero/empty values denote that the specification doesn't matter
+type Specs struct {
+	// Lifting capacity in tonnes, Ex:- 500 tonne crane
+	CapacityTonnes float64 `json:"capacity_tonnes,omitempty" bson:"capacity_tonnes,omitempty"`
+
+	// Length of the boom in metres for cranes and lifters
+	BoomLengthMetres float64 `json:"boom_length_metres,omitempty" bson:"boom_length_metres,omitempty"`
+
+	// Maximum working height in metres for lifters
+	WorkingHeightMetres float64 `json:"working_height_metres,omitempty" bson:"working_height_metres,omitempty"`
+
+	// Carrying capacity in litres for tankers
+	VolumeLitres float64 `json:"volume_litres,omitempty" bson:"volume_litres,omitempty"`
+
+	// Fuel used by the equipment, Ex:- diesel, petrol, electric, cng
+	FuelType string `json:"fuel_type,omitempty" bson:"fuel_type,omitempty"`
+}
+
+// NumericFields returns the numeric specifications in the form of <bson key>:<value>
+func (specs Specs) NumericFields() map[string]float64 {
+	return map[string]float64{
+		"capacity_tonnes":       specs.CapacityTonnes,
+		"boom_length_metres":    specs.BoomLengthMetres,
+		"working_height_metres": specs.WorkingHeightMetres,
+		"volume_litres":         specs.VolumeLitres,
+	}
+}
+
+// IsEmpty checks whether none of the specifications are provided
+func (specs Specs) IsEmpty() bool {
+	return specs == Specs{}
+}
+
+// Normalize converts the specifications into their canonical form for comparisons
+func (specs *Specs) Normalize() {
+	specs.FuelType = strings.ToLower(strings.TrimSpace(specs.FuelType))
+}
+
+// Validate checks whether the numeric specifications are non-negative
+func (specs Specs) Validate() error {
+	for key, value := range specs.NumericFields() {
+		if value < 0 {
+			return fmt.Errorf("Specification %s cannot be negative", key)
+		}
+	}
+	return nil
+}
+
+// Shortfalls returns the bson keys of the required specifications which are not met
+func (specs Specs) Shortfalls(required Specs) []string {
+	shortfalls := make([]string, 0)
+	available := specs.NumericFields()
+	for key, value := range required.NumericFields() {
+		if value > 0 && available[key] < value {
+			shortfalls = append(shortfalls, key)
+		}
+	}
+	if required.FuelType != EMPTY && required.FuelType != specs.FuelType {
+		shortfalls = append(shortfalls, "fuel_type")
+	}
+	sort.Strings(shortfalls)
+	return shortfalls
+}
+
+// EquipmentSpecs stores the specifications of items in the form of <equipment key>:<specifications>
+type EquipmentSpecs map[string]Specs
+
+// Validate checks whether the specifications belong to the items of the given inventory and are non-negative
+// The specifications are normalized in the process
+func (equipmentSpecs EquipmentSpecs) Validate(inventory Inventory) error {
+	for key, specs := range equipmentSpecs {
+		if _, ok := inventory[key]; !ok {
+			return fmt.Errorf("Specifications provided for %s which is not a part of the items", key)
+		}
+		if err := specs.Validate(); err != nil {
+			return fmt.Errorf("%s: %s", key, err.Error())
+		}
+		specs.Normalize()
+		equipmentSpecs[key] = specs
+	}
+	return nil
+}
+
+// CheckAgainst verifies that the specifications of every offered item meet the required specifications
+func (equipmentSpecs EquipmentSpecs) CheckAgainst(required EquipmentSpecs, offer Inventory) error {
+	for key, value := range offer {
+		if value == 0 {
+			continue
+		}
+		if shortfalls := equipmentSpecs[key].Shortfalls(required[key]); len(shortfalls) > 0 {
+			return fmt.Errorf("%s does not meet the required specifications: %s", key, strings.Join(shortfalls, ", "))
+		}
+	}
+	return nil
+}
+
+// Subset returns the specifications of only those items which are present in the inventory
+func (equipmentSpecs EquipmentSpecs) Subset(inventory Inventory) EquipmentSpecs {
+	subset := make(EquipmentSpecs)
+	for key, value := range inventory {
+		if specs, ok := equipmentSpecs[key]; ok && value != 0 && !specs.IsEmpty() {
+			subset[key] = specs
+		}
+	}
+	return subset
+}
+
+// Merge returns new specifications holding the items of both, the given specifications take precedence
+func (equipmentSpecs EquipmentSpecs) Merge(other EquipmentSpecs) EquipmentSpecs {
+	merged := make(EquipmentSpecs)
+	for key, specs := range equipmentSpecs {
+		merged[key] = specs
+	}
+	for key, specs := range other {
+		merged[key] = specs
+	}
+	return merged
+}
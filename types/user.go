@@ -55,7 +55,17 @@ type User struct {
 	OfficeAddress string     `json:"office_address" bson:"office_address" binding:"required" valid:"required~Field 'office_address' is required but was not provided"`
 	Role          string     `json:"-" bson:"role"`
 	Inventory     *Inventory `json:"inventory,omitempty" bson:"inventory,omitempty"`
-	Verified      bool       `json:"-" bson:"verified"`
+	// Specifications of the items in the vendor's inventory
+	InventorySpecs EquipmentSpecs `json:"inventory_specs,omitempty" bson:"inventory_specs,omitempty"`
+	Verified       bool           `json:"-" bson:"verified"`
+	// Suspended users cannot log in or use the platform until an admin lifts the suspension
+	Suspended bool `json:"suspended,omitempty" bson:"suspended,omitempty"`
+	// Aggregate of the ratings received after the completion of posts
+	Rating *RatingSummary `json:"rating,omitempty" bson:"rating,omitempty"`
+	// Base location of a vendor's fleet, vendors are invited to the posts created near it
+	Location *Location `json:"location,omitempty" bson:"location,omitempty"`
+	// InvitationEmails denotes whether a vendor receives its invitations to matching posts over email as well
+	InvitationEmails bool `json:"invitation_emails,omitempty" bson:"invitation_emails,omitempty"`
 }
 
 // GetName returns the user's username
@@ -98,3 +108,25 @@ func (user *User) GetRole() string {
 func (user *User) IsVerified() bool {
 	return user.Verified
 }
+
+// IsSuspended checks whether the user's account has been suspended or not
+func (user *User) IsSuspended() bool {
+	return user.Suspended
+}
+
+// MatchingPreferences is the body of a vendor's request for updating how it is matched with new posts
+type MatchingPreferences struct {
+	Location         *Location `json:"location" valid:"required"`
+	InvitationEmails bool      `json:"invitation_emails"`
+}
+
+// UserQuery holds the optional filters for searching users
+type UserQuery struct {
+	// Search is matched against the email ID, username and company of the users
+	Search string
+	Role   string
+	// Suspended restricts the search to suspended users
+	Suspended bool
+	// Unverified restricts the search to users who haven't verified their email
+	Unverified bool
+}